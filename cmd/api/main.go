@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
 	httpapi "github.com/denisok6893-rgb/ai-property-matching/internal/http"
@@ -15,18 +20,26 @@ type Config struct {
 	Address        string
 	PropertiesPath string
 	WeightsPath    string
+	PresetsDir     string
+	WeightsWatch   time.Duration // 0 disables polling the weights file
+	AdminToken     string
 	Storage        string
 	DBPath         string
+	JournalPath    string        // memory mode: mutations journal; empty disables persistence
+	SnapshotPath   string        // memory mode: compacted state, replaces PropertiesPath once written
+	CompactEvery   time.Duration // memory mode: journal compaction interval
+	PriceBuckets   []float64     // GET /properties price facet edges; nil = defaults
 }
 
 func main() {
 	cfg := loadConfig()
 
-        var (
-            props []domain.Property
-            err   error
-            store *storage.SQLiteStore
-        )
+	var (
+		props    []domain.Property
+		err      error
+		store    *storage.SQLiteStore
+		memStore *storage.MemoryStore // journaled memory mode
+	)
 
 	switch cfg.Storage {
 	case "sqlite":
@@ -36,8 +49,12 @@ func main() {
 			log.Fatalf("open sqlite: %v", err)
 		}
 
-		if err := store.EnsureSchema(); err != nil {
-			log.Fatalf("sqlite schema: %v", err)
+		applied, err := store.Migrate()
+		if err != nil {
+			log.Fatalf("sqlite migrate: %v", err)
+		}
+		for _, m := range applied {
+			log.Printf("sqlite: applied migration %d (%s)", m.Version, m.Name)
 		}
 
 		n, err := store.CountProperties()
@@ -55,12 +72,14 @@ func main() {
 			}
 		}
 
-		props, _, err = store.ListProperties(20, 0)
-		if err != nil {
-			log.Fatalf("sqlite list: %v", err)
-		}
-
 	default: // memory
+		if cfg.JournalPath != "" {
+			memStore, err = storage.OpenJournaledStore(cfg.PropertiesPath, cfg.SnapshotPath, cfg.JournalPath)
+			if err != nil {
+				log.Fatalf("open journal: %v", err)
+			}
+			break
+		}
 		props, err = storage.LoadPropertiesFromFile(cfg.PropertiesPath)
 		if err != nil {
 			log.Fatalf("load properties: %v", err)
@@ -74,10 +93,43 @@ func main() {
 	}
 
 	engine := matching.NewEngine(w)
+	if presets, err := matching.LoadPresetDocs(cfg.PresetsDir, w); err != nil {
+		log.Printf("no weight presets (reason: %v)", err)
+	} else if err := engine.SetPresets(presets); err != nil {
+		log.Printf("weight presets: %v", err)
+	}
+	if cfg.AdminToken == "" {
+		log.Printf("ADMIN_TOKEN not set: /admin/* answers 403")
+	}
+
 	srv := httpapi.NewServer(engine, props)
-        if cfg.Storage == "sqlite" && store != nil {
-            srv.PropsRepo = &httpapi.SQLitePropertiesRepo{Store: store}
-        }
+	srv.AdminToken = cfg.AdminToken
+	srv.PriceBuckets = cfg.PriceBuckets
+
+	// Weights hot-reload: on SIGHUP and when the file changes on disk.
+	go engine.WatchWeightsFile(context.Background(), cfg.WeightsPath, cfg.WeightsWatch, log.Printf)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			v, err := engine.ReloadFromFile(cfg.WeightsPath)
+			if err != nil && !errors.Is(err, matching.ErrPresetDropped) {
+				log.Printf("SIGHUP: weights reload rejected: %v", err)
+				continue
+			}
+			log.Printf("SIGHUP: weights reloaded (version %d)", v.Version)
+			if err != nil {
+				log.Printf("SIGHUP: %v", err)
+			}
+		}
+	}()
+	if memStore != nil {
+		srv.PropsRepo = &httpapi.InMemoryPropertiesRepo{Store: memStore}
+		go memStore.RunCompaction(context.Background(), cfg.CompactEvery, log.Printf)
+	}
+	if cfg.Storage == "sqlite" && store != nil {
+		srv.PropsRepo = &httpapi.SQLitePropertiesRepo{Store: store} // also the /match catalog
+	}
 
 	log.Printf("API listening on %s", cfg.Address)
 	if err := http.ListenAndServe(cfg.Address, srv.Routes()); err != nil {
@@ -86,12 +138,20 @@ func main() {
 }
 
 func loadConfig() Config {
+	journal := os.Getenv("JOURNAL_PATH")
 	return Config{
 		Address:        getEnv("API_ADDRESS", ":8080"),
 		PropertiesPath: getEnv("PROPERTIES_PATH", "data/properties.json"),
 		WeightsPath:    getEnv("WEIGHTS_PATH", "configs/weights.json"),
+		PresetsDir:     getEnv("PRESETS_DIR", "configs/presets"),
+		WeightsWatch:   getEnvDuration("WEIGHTS_WATCH_INTERVAL", 5*time.Second),
+		AdminToken:     os.Getenv("ADMIN_TOKEN"),
 		Storage:        getEnv("STORAGE", "memory"), // memory | sqlite
 		DBPath:         getEnv("DB_PATH", "data/app.db"),
+		JournalPath:    journal,
+		SnapshotPath:   getEnv("SNAPSHOT_PATH", journal+".snapshot.json"),
+		CompactEvery:   getEnvDuration("COMPACT_INTERVAL", 10*time.Minute),
+		PriceBuckets:   getEnvPriceBuckets("PRICE_BUCKETS"),
 	}
 }
 
@@ -101,3 +161,29 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid %s=%q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+func getEnvPriceBuckets(key string) []float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	edges, err := httpapi.ParsePriceBuckets(v)
+	if err != nil {
+		log.Printf("invalid %s=%q (%v), using defaults", key, v, err)
+		return nil
+	}
+	return edges
+}
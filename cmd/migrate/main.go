@@ -0,0 +1,68 @@
+// Command migrate shows or applies SQLite schema migrations.
+//
+//	go run ./cmd/migrate [-db data/app.db] status
+//	go run ./cmd/migrate [-db data/app.db] up
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+func main() {
+	dbPath := flag.String("db", getEnv("DB_PATH", "data/app.db"), "SQLite database file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: migrate [-db path] status|up\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	store, err := storage.OpenSQLite(*dbPath)
+	if err != nil {
+		log.Fatalf("open sqlite: %v", err)
+	}
+	defer store.Close()
+
+	switch flag.Arg(0) {
+	case "status":
+		st, err := store.MigrationStatus()
+		if err != nil {
+			log.Fatalf("status: %v", err)
+		}
+		for _, m := range st {
+			state := "pending"
+			if m.AppliedAt != nil {
+				state = "applied " + m.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-40s %s\n", m.Version, m.Name, state)
+		}
+	case "up":
+		applied, err := store.Migrate()
+		for _, m := range applied {
+			fmt.Printf("applied %d  %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		fmt.Printf("schema is at version %d\n", storage.SchemaVersion())
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
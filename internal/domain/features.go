@@ -0,0 +1,47 @@
+package domain
+
+// featureFields lists Features by JSON key. Storage uses the keys as column
+// names, matching and the API as the names of min_features and weights.
+var featureFields = []struct {
+	key string
+	ptr func(*Features) *float64
+}{
+	{"quietness", func(f *Features) *float64 { return &f.Quietness }},
+	{"sun_exposure", func(f *Features) *float64 { return &f.SunExposure }},
+	{"wind_protection", func(f *Features) *float64 { return &f.WindProtection }},
+	{"tourism_intensity", func(f *Features) *float64 { return &f.TourismIntensity }},
+	{"family_friendly", func(f *Features) *float64 { return &f.FamilyFriendly }},
+	{"expat_friendly", func(f *Features) *float64 { return &f.ExpatFriendly }},
+	{"investment_potential", func(f *Features) *float64 { return &f.InvestmentPotential }},
+	{"distance_to_sea_km", func(f *Features) *float64 { return &f.DistanceToSeaKm }},
+	{"walkability", func(f *Features) *float64 { return &f.Walkability }},
+	{"green_areas", func(f *Features) *float64 { return &f.GreenAreas }},
+}
+
+// FeatureKeys returns the JSON keys of Features in declaration order.
+func FeatureKeys() []string {
+	out := make([]string, len(featureFields))
+	for i, c := range featureFields {
+		out[i] = c.key
+	}
+	return out
+}
+
+// FeatureValue returns a feature by its JSON key.
+func FeatureValue(f Features, key string) (float64, bool) {
+	if p := FeaturePtr(&f, key); p != nil {
+		return *p, true
+	}
+	return 0, false
+}
+
+// FeaturePtr returns the field of f named by its JSON key, or nil for an
+// unknown key.
+func FeaturePtr(f *Features, key string) *float64 {
+	for _, c := range featureFields {
+		if c.key == key {
+			return c.ptr(f)
+		}
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type ClientProfile struct {
 	Name               string            `json:"name"`
 	LocationPreference string            `json:"location_preference"`
@@ -12,7 +14,18 @@ type ClientProfile struct {
 }
 
 type HardFilters struct {
-	MustHaveAmenities []string `json:"must_have_amenities"`
+	MustHaveAmenities  []string           `json:"must_have_amenities"`
+	ExcludedAmenities  []string           `json:"excluded_amenities"`
+	MinBedrooms        int                `json:"min_bedrooms"`
+	MaxBedrooms        int                `json:"max_bedrooms"`
+	MinBathrooms       int                `json:"min_bathrooms"`
+	MaxBathrooms       int                `json:"max_bathrooms"`
+	MinAreaSQM         float64            `json:"min_area_sqm"`
+	MaxAreaSQM         float64            `json:"max_area_sqm"`
+	AllowedLocations   []string           `json:"allowed_locations"`
+	BlockedLocations   []string           `json:"blocked_locations"`
+	MaxDistanceToSeaKm float64            `json:"max_distance_to_sea_km"`
+	MinFeatures        map[string]float64 `json:"min_features"` // features JSON key -> minimum value
 }
 
 type PreferenceWeights struct {
@@ -29,17 +42,31 @@ type PreferenceWeights struct {
 }
 
 type Property struct {
-	ID        string   `json:"id"`
-	Title     string   `json:"title"`
-	Location  string   `json:"location"`
-	Price     float64  `json:"price"`
-	Bedrooms  int      `json:"bedrooms"`
-	Bathrooms int      `json:"bathrooms"`
-	AreaSQM   float64  `json:"area_sqm"`
-      	Description string   `json:"description"`
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Location    string   `json:"location"`
+	Price       float64  `json:"price"`
+	Bedrooms    int      `json:"bedrooms"`
+	Bathrooms   int      `json:"bathrooms"`
+	AreaSQM     float64  `json:"area_sqm"`
+	Description string   `json:"description"`
 	ImageURLs   []string `json:"image_urls"`
-	Amenities []string `json:"amenities"`
-	Features  Features `json:"features"`
+	Amenities   []string `json:"amenities"`
+	Features    Features `json:"features"`
+
+	// Coordinates is nil when the position is unknown.
+	Coordinates *GeoPoint `json:"coordinates,omitempty"`
+
+	// Version is bumped on every update and backs the ETag of GET /properties/{id}.
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GeoPoint is a WGS 84 position in degrees.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
 }
 
 type Features struct {
@@ -66,3 +93,57 @@ type ScoreReason struct {
 	Message string  `json:"message"`
 	Impact  float64 `json:"impact"`
 }
+
+// Rejection describes a property excluded by hard filters.
+type Rejection struct {
+	PropertyID string          `json:"property_id"`
+	Title      string          `json:"title"`
+	Failures   []FilterFailure `json:"failures"`
+}
+
+type FilterFailure struct {
+	Constraint string `json:"constraint"`
+	Message    string `json:"message"`
+}
+
+// Relaxation is a suggested change to a profile's hard constraints and how many
+// extra properties it would let through.
+type Relaxation struct {
+	Constraint string        `json:"constraint"`
+	Message    string        `json:"message"`
+	Unlocked   int           `json:"unlocked"`
+	FillsLimit bool          `json:"fills_limit"`
+	Profile    ClientProfile `json:"profile"`
+}
+
+// ScoreBreakdown is the full audit of how a property's score was reached.
+type ScoreBreakdown struct {
+	PropertyID string  `json:"property_id"`
+	Score      float64 `json:"score"`
+	// PassesHardFilters is false when the property would be excluded from /match;
+	// the score is still computed for reference.
+	PassesHardFilters  bool              `json:"passes_hard_filters"`
+	HardFilterFailures []FilterFailure   `json:"hard_filter_failures,omitempty"`
+	TotalWeight        float64           `json:"total_weight"`
+	Factors            []FactorBreakdown `json:"factors"`
+}
+
+// FactorBreakdown is one factor's part of the score. For nudges Weight is the
+// share of the accumulated weight they add. Points sum up to Score; Share is
+// the factor's fraction of it.
+type FactorBreakdown struct {
+	Key             string   `json:"key"`
+	Label           string   `json:"label"`
+	Kind            string   `json:"kind"` // factor | nudge
+	Active          bool     `json:"active"`
+	Raw             *float64 `json:"raw,omitempty"`
+	Curve           string   `json:"curve,omitempty"`
+	Value           float64  `json:"value"`
+	Weight          float64  `json:"weight"`
+	Priority        float64  `json:"priority"`
+	EffectiveWeight float64  `json:"effective_weight"`
+	Contribution    float64  `json:"contribution"`
+	Points          float64  `json:"points"`
+	Share           float64  `json:"share"`
+	Message         string   `json:"message"`
+}
@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/matching"
+)
+
+// maxWeightsBody caps PUT /admin/weights payloads.
+const maxWeightsBody = 1 << 20
+
+type WeightsResponse struct {
+	matching.WeightsVersion
+	Weights matching.Weights `json:"weights"`
+}
+
+// handleAdminWeights serves GET/PUT /admin/weights.
+// PUT takes the same JSON as configs/weights.json (missing fields fall back to
+// defaults) and swaps it into the engine atomically, presets included. The
+// change is in-memory only: a later file reload or SIGHUP replaces it. Without
+// an AdminToken the endpoint is disabled (403).
+func (s *Server) handleAdminWeights(w http.ResponseWriter, r *http.Request) {
+	if s.AdminToken == "" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin_disabled"})
+		return
+	}
+	if !s.adminAuthorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		snap := s.Engine.Snapshot()
+		writeJSON(w, http.StatusOK, WeightsResponse{WeightsVersion: snap.Version(), Weights: snap.Weights()})
+
+	case http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWeightsBody))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_body"})
+			return
+		}
+		weights, err := matching.ApplyOverride(matching.DefaultWeights(), body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_weights", "detail": err.Error()})
+			return
+		}
+		v, err := s.Engine.SetWeights(weights)
+		if err != nil {
+			log.Printf("admin weights: %v", err) // the weights are in place, only presets were dropped
+		}
+		writeJSON(w, http.StatusOK, WeightsResponse{WeightsVersion: v, Weights: weights})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// adminAuthorized checks "Authorization: Bearer <AdminToken>"; without a
+// configured token nothing is authorized.
+func (s *Server) adminAuthorized(r *http.Request) bool {
+	if s.AdminToken == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(s.AdminToken)) == 1
+}
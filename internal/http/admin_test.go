@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/matching"
+)
+
+func TestAdminWeights_GetPut(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(matching.NewEngine(matching.DefaultWeights()), nil)
+	srv.AdminToken = "secret"
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	do := func(method, body, token string) (int, WeightsResponse) {
+		req, _ := http.NewRequest(method, ts.URL+"/admin/weights", bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s /admin/weights: %v", method, err)
+		}
+		defer resp.Body.Close()
+		var got WeightsResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return resp.StatusCode, got
+	}
+
+	if code, _ := do(http.MethodGet, "", ""); code != http.StatusUnauthorized {
+		t.Fatalf("no token status=%d", code)
+	}
+
+	code, got := do(http.MethodGet, "", "secret")
+	if code != http.StatusOK || got.Version != 1 || got.Weights.Quietness != matching.DefaultWeights().Quietness {
+		t.Fatalf("GET status=%d body=%+v", code, got)
+	}
+
+	code, got = do(http.MethodPut, `{"quietness": 0.25}`, "secret")
+	if code != http.StatusOK || got.Version != 2 || got.Weights.Quietness != 0.25 || got.UpdatedAt.IsZero() {
+		t.Fatalf("PUT status=%d body=%+v", code, got)
+	}
+	if srv.Engine.Weights().Quietness != 0.25 {
+		t.Fatalf("engine not updated")
+	}
+
+	if code, _ = do(http.MethodPut, `{"curves": {"sea_proximity": {"type": "sigmoid"}}}`, "secret"); code != http.StatusBadRequest {
+		t.Fatalf("invalid PUT status=%d", code)
+	}
+	if v := srv.Engine.Version().Version; v != 2 {
+		t.Fatalf("invalid PUT must not bump version, got %d", v)
+	}
+}
+
+func TestAdminWeights_DisabledWithoutToken(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(matching.NewEngine(matching.DefaultWeights()), nil)
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	for _, token := range []string{"", "anything"} {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/admin/weights", bytes.NewBufferString(`{"quietness": 0.25}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT /admin/weights: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("token=%q status=%d want 403", token, resp.StatusCode)
+		}
+	}
+	if srv.Engine.Version().Version != 1 {
+		t.Fatalf("weights changed without a token")
+	}
+}
@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"context"
+	"iter"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+// Catalog is the set of properties /match and /match/explain score against.
+type Catalog interface {
+	// Scan calls fn for every property until fn returns false. With a non-nil
+	// profile the backend may skip properties failing its hard filters; the
+	// engine checks them again, so a partial prefilter is fine.
+	Scan(ctx context.Context, profile *domain.ClientProfile, fn func(domain.Property) bool) error
+	Get(ctx context.Context, id string) (domain.Property, bool, error)
+}
+
+// snapshotter is implemented by catalogs that can pin their current state.
+type snapshotter interface {
+	snapshot() Catalog
+}
+
+// catalogSeq adapts Catalog.Scan for the engine; a scan error is stored in *errp.
+func catalogSeq(ctx context.Context, c Catalog, profile *domain.ClientProfile, errp *error) iter.Seq[domain.Property] {
+	return func(yield func(domain.Property) bool) {
+		if err := c.Scan(ctx, profile, yield); err != nil && *errp == nil {
+			*errp = err
+		}
+	}
+}
+
+// hardFilter is the storage prefilter equivalent of the profile's budget and
+// hard filters, for catalogs that can filter before the engine does.
+func hardFilter(profile domain.ClientProfile) storage.PropertyFilter {
+	hf := profile.HardFilters
+	f := storage.PropertyFilter{
+		Locations:         hf.AllowedLocations,
+		BlockedLocations:  hf.BlockedLocations,
+		MinPrice:          profile.BudgetMin,
+		MaxPrice:          profile.BudgetMax,
+		MinBedrooms:       hf.MinBedrooms,
+		MaxBedrooms:       hf.MaxBedrooms,
+		MinBathrooms:      hf.MinBathrooms,
+		MaxBathrooms:      hf.MaxBathrooms,
+		MinAreaSQM:        hf.MinAreaSQM,
+		MaxAreaSQM:        hf.MaxAreaSQM,
+		Amenities:         hf.MustHaveAmenities,
+		ExcludedAmenities: hf.ExcludedAmenities,
+	}
+	if hf.MaxDistanceToSeaKm > 0 {
+		f.MaxFeatures = map[string]float64{"distance_to_sea_km": hf.MaxDistanceToSeaKm}
+	}
+	if len(hf.MinFeatures) > 0 {
+		// the engine looks features up case-insensitively
+		f.MinFeatures = make(map[string]float64, len(hf.MinFeatures))
+		for k, v := range hf.MinFeatures {
+			f.MinFeatures[strings.ToLower(strings.TrimSpace(k))] = v
+		}
+	}
+	return f
+}
+
+// catalog returns Catalog, or the properties repo when it can be scanned.
+func (s *Server) catalog() Catalog {
+	if s.Catalog != nil {
+		return s.Catalog
+	}
+	if c, ok := s.PropsRepo.(Catalog); ok {
+		return c
+	}
+	return snapshotCatalog(nil)
+}
+
+// matchCatalog is catalog pinned to a snapshot when the backend supports it,
+// for handlers that scan more than once.
+func (s *Server) matchCatalog() Catalog {
+	c := s.catalog()
+	if sn, ok := c.(snapshotter); ok {
+		return sn.snapshot()
+	}
+	return c
+}
+
+// repo returns PropsRepo; NewServer sets it to an in-memory repo.
+func (s *Server) repo() PropertiesRepo {
+	return s.PropsRepo
+}
@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+// propertyFields are the fields= names of domain.Property by JSON key; the
+// search snippet and distance_km come on top. features.<key> selects a
+// single feature.
+var propertyFields = map[string]func(p domain.Property) any{
+	"id":          func(p domain.Property) any { return p.ID },
+	"title":       func(p domain.Property) any { return p.Title },
+	"location":    func(p domain.Property) any { return p.Location },
+	"price":       func(p domain.Property) any { return p.Price },
+	"bedrooms":    func(p domain.Property) any { return p.Bedrooms },
+	"bathrooms":   func(p domain.Property) any { return p.Bathrooms },
+	"area_sqm":    func(p domain.Property) any { return p.AreaSQM },
+	"description": func(p domain.Property) any { return p.Description },
+	"image_urls":  func(p domain.Property) any { return p.ImageURLs },
+	"amenities":   func(p domain.Property) any { return p.Amenities },
+	"features":    func(p domain.Property) any { return p.Features },
+	"coordinates": func(p domain.Property) any { return p.Coordinates },
+	"version":     func(p domain.Property) any { return p.Version },
+	"created_at":  func(p domain.Property) any { return p.CreatedAt },
+	"updated_at":  func(p domain.Property) any { return p.UpdatedAt },
+}
+
+const featuresPrefix = "features."
+
+// fieldSet is a parsed fields= list. The id is always included.
+type fieldSet struct {
+	fields   []string // top-level names in request order, without features.*
+	features []string // feature keys picked one by one
+	snippet  bool
+	distance bool
+}
+
+// parseFields parses "title,price,features.quietness". Unknown names and an
+// empty list are errors.
+func parseFields(v string) (*fieldSet, error) {
+	fs := &fieldSet{fields: []string{"id"}}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if name == "id" {
+			continue
+		}
+		switch key, ok := strings.CutPrefix(name, featuresPrefix); {
+		case name == "snippet":
+			fs.snippet = true
+		case name == "distance_km":
+			fs.distance = true
+		case ok:
+			if _, known := domain.FeatureValue(domain.Features{}, key); !known {
+				return nil, fmt.Errorf("unknown field %q", name)
+			}
+			fs.features = append(fs.features, key)
+		default:
+			if _, known := propertyFields[name]; !known {
+				return nil, fmt.Errorf("unknown field %q", name)
+			}
+			fs.fields = append(fs.fields, name)
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no fields")
+	}
+	return fs, nil
+}
+
+// project returns the selected fields of p; snippet is only set with a text
+// query in f and distance_km with a near point.
+func (fs *fieldSet) project(p domain.Property, f storage.PropertyFilter) map[string]any {
+	out := make(map[string]any, len(fs.fields)+2)
+	for _, name := range fs.fields {
+		out[name] = propertyFields[name](p)
+	}
+	// the whole features object wins over single keys
+	if _, whole := out["features"]; !whole && len(fs.features) > 0 {
+		features := make(map[string]float64, len(fs.features))
+		for _, key := range fs.features {
+			features[key], _ = domain.FeatureValue(p.Features, key)
+		}
+		out["features"] = features
+	}
+	if fs.snippet && f.Text != nil {
+		out["snippet"] = f.Text.Snippet(p)
+	}
+	if d := distanceKm(p, f); fs.distance && d != nil {
+		out["distance_km"] = *d
+	}
+	return out
+}
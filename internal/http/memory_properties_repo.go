@@ -0,0 +1,76 @@
+package httpapi
+
+import (
+	"context"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+// InMemoryPropertiesRepo serves properties from a storage.MemoryStore.
+type InMemoryPropertiesRepo struct {
+	Store *storage.MemoryStore
+}
+
+func (r *InMemoryPropertiesRepo) List(_ context.Context, p ListParams) ([]domain.Property, int, *storage.Cursor, error) {
+	page, total, next := r.Store.ListPropertiesFiltered(p.Filter, p.Page)
+	return page, total, next, nil
+}
+
+func (r *InMemoryPropertiesRepo) Get(_ context.Context, id string) (domain.Property, bool, error) {
+	p, ok := r.Store.GetProperty(id)
+	return p, ok, nil
+}
+
+func (r *InMemoryPropertiesRepo) Create(_ context.Context, p domain.Property) (domain.Property, error) {
+	return r.Store.CreateProperty(p)
+}
+
+func (r *InMemoryPropertiesRepo) Update(_ context.Context, p domain.Property, ifVersion int64) (domain.Property, bool, error) {
+	return r.Store.UpdateProperty(p, ifVersion)
+}
+
+func (r *InMemoryPropertiesRepo) Delete(_ context.Context, id string) (bool, error) {
+	return r.Store.DeleteProperty(id)
+}
+
+func (r *InMemoryPropertiesRepo) Facets(_ context.Context, f storage.PropertyFilter, req storage.FacetRequest) (storage.Facets, error) {
+	var matched []domain.Property
+	for _, p := range r.Store.Snapshot() {
+		if f.Matches(p) {
+			matched = append(matched, p)
+		}
+	}
+	return storage.CountFacets(matched, req), nil
+}
+
+// Scan implements Catalog.
+func (r *InMemoryPropertiesRepo) Scan(ctx context.Context, profile *domain.ClientProfile, fn func(domain.Property) bool) error {
+	return r.snapshot().Scan(ctx, profile, fn)
+}
+
+// snapshot pins the current properties, so a match sees one consistent catalog.
+func (r *InMemoryPropertiesRepo) snapshot() Catalog {
+	return snapshotCatalog(r.Store.Snapshot())
+}
+
+// snapshotCatalog is an immutable list of properties.
+type snapshotCatalog []domain.Property
+
+func (c snapshotCatalog) Scan(_ context.Context, _ *domain.ClientProfile, fn func(domain.Property) bool) error {
+	for _, p := range c {
+		if !fn(p) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c snapshotCatalog) Get(_ context.Context, id string) (domain.Property, bool, error) {
+	for _, p := range c {
+		if p.ID == id {
+			return p, true, nil
+		}
+	}
+	return domain.Property{}, false, nil
+}
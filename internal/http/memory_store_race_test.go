@@ -0,0 +1,102 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/matching"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+// Запускать с -race: create/patch/delete идут параллельно с /match и списком.
+func TestMemoryStore_ConcurrentCRUDAndMatch(t *testing.T) {
+	t.Parallel()
+
+	var seed []domain.Property
+	for i := 0; i < 20; i++ {
+		seed = append(seed, domain.Property{ID: fmt.Sprintf("s-%d", i), Title: "S", Location: "Valencia", Price: 300000})
+	}
+	ts := httptest.NewServer(NewServer(matching.NewEngine(matching.DefaultWeights()), seed).Routes())
+	defer ts.Close()
+
+	do := func(method, path, body string) int {
+		req, _ := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("%s %s: %v", method, path, err)
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				do(http.MethodPost, "/properties", `{"title": "N", "location": "Valencia", "price": 250000}`)
+			}
+		}()
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < 20; i += 4 {
+				do(http.MethodPatch, fmt.Sprintf("/properties/s-%d", i), `{"price": 310000}`)
+				if code := do(http.MethodDelete, fmt.Sprintf("/properties/s-%d", i), ""); code != http.StatusOK {
+					t.Errorf("DELETE s-%d status=%d", i, code)
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				if code := do(http.MethodPost, "/match", `{"profile": {"budget_max": 400000}, "limit": 5}`); code != http.StatusOK {
+					t.Errorf("POST /match status=%d", code)
+				}
+				do(http.MethodGet, "/properties?limit=50", "")
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp, err := http.Get(ts.URL + "/properties?limit=500")
+	if err != nil {
+		t.Fatalf("GET /properties: %v", err)
+	}
+	defer resp.Body.Close()
+	var got PropertiesListResponse
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got.Total != 100 {
+		t.Fatalf("total=%d want 100 created, all seeds deleted", got.Total)
+	}
+}
+
+func TestMemoryStore_SnapshotIsStable(t *testing.T) {
+	t.Parallel()
+
+	store := storage.NewMemoryStore([]domain.Property{{ID: "a", Price: 1}, {ID: "b", Price: 2}})
+	snap := store.Snapshot()
+
+	store.CreateProperty(domain.Property{Title: "c"})
+	if _, _, err := store.UpdateProperty(domain.Property{ID: "a", Price: 10}, 1); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	store.DeleteProperty("b")
+
+	if len(snap) != 2 || snap[0].Price != 1 || snap[1].ID != "b" {
+		t.Fatalf("snapshot changed: %+v", snap)
+	}
+	if got := store.Snapshot(); len(got) != 2 || got[0].Price != 10 || got[0].Version != 2 {
+		t.Fatalf("current=%+v", got)
+	}
+	if _, _, err := store.UpdateProperty(domain.Property{ID: "a"}, 1); err != storage.ErrVersionConflict {
+		t.Fatalf("stale update err=%v", err)
+	}
+}
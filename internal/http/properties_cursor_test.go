@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+func TestGETProperties_CursorPagination(t *testing.T) {
+	t.Parallel()
+
+	// цены повторяются, у части объектов нет площади — проверяем связки и NULL
+	var seed []domain.Property
+	for i := 0; i < 23; i++ {
+		seed = append(seed, domain.Property{
+			ID: fmt.Sprintf("p%02d", i), Title: "Flat", Location: "X",
+			Price: float64(100000 + (i%5)*50000), AreaSQM: float64((i % 4) * 40), Bedrooms: i % 3,
+			Description: map[bool]string{true: "terrace terrace", false: "terrace"}[i%3 == 0],
+		})
+	}
+	memTS := httptest.NewServer(NewServer(nil, seed).Routes())
+	defer memTS.Close()
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany(seed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	get := func(base, query string) (int, PropertiesListResponse) {
+		resp, err := http.Get(base + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		defer resp.Body.Close()
+		var got PropertiesListResponse
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		return resp.StatusCode, got
+	}
+	// walk collects every id by following next_cursor from the first page
+	walk := func(base, query string) []string {
+		var ids []string
+		cursor := ""
+		for page := 0; ; page++ {
+			q := query + "&limit=4"
+			if cursor != "" {
+				q += "&cursor=" + url.QueryEscape(cursor)
+			}
+			code, got := get(base, q)
+			if code != http.StatusOK || page > 10 {
+				t.Fatalf("%s page %d: status=%d", query, page, code)
+			}
+			for _, it := range got.Items {
+				ids = append(ids, it.ID)
+			}
+			if got.NextCursor == "" {
+				return ids
+			}
+			cursor = got.NextCursor
+		}
+	}
+
+	for _, query := range []string{"sort=", "sort=price", "sort=-price,bedrooms", "sort=price_per_sqm,-area",
+		"sort=-price_per_sqm", "sort=created_at", "q=terrace", "q=terrace&sort=-bedrooms", "min_price=150000&sort=-area"} {
+		// курсоры дают тот же порядок, что и одна большая страница
+		_, all := get(memTS.URL, query+"&limit=100")
+		var want []string
+		for _, it := range all.Items {
+			want = append(want, it.ID)
+		}
+		for name, base := range map[string]string{"memory": memTS.URL, "sqlite": sqlTS.URL} {
+			if got := walk(base, query); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s %s:\n got %v\nwant %v", name, query, got, want)
+			}
+		}
+	}
+
+	// новый объект перед курсором не сдвигает следующую страницу
+	for name, ts := range map[string]*httptest.Server{"memory": memTS, "sqlite": sqlTS} {
+		_, first := get(ts.URL, "sort=price&limit=5")
+		_, second := get(ts.URL, "sort=price&limit=5&cursor="+url.QueryEscape(first.NextCursor))
+		if code, _ := doJSON(t, http.MethodPost, ts.URL+"/properties", `{"title": "cheap", "location": "X", "price": 1}`); code != http.StatusCreated {
+			t.Fatalf("%s create status=%d", name, code)
+		}
+		_, again := get(ts.URL, "sort=price&limit=5&cursor="+url.QueryEscape(first.NextCursor))
+		if !reflect.DeepEqual(second.Items, again.Items) {
+			t.Fatalf("%s: page shifted after insert: %v vs %v", name, second.Items, again.Items)
+		}
+	}
+
+	_, page := get(memTS.URL, "sort=price&limit=5")
+	bad := map[string]string{
+		"cursor=!!!":    "invalid_cursor",
+		"cursor=bm9wZQ": "invalid_cursor", // "nope"
+		"sort=-price&cursor=" + url.QueryEscape(page.NextCursor):                                                         "invalid_cursor",
+		"sort=price&offset=5&cursor=" + url.QueryEscape(page.NextCursor):                                                 "cursor_with_offset",
+		"sort=price_per_sqm&cursor=" + (&storage.Cursor{Order: "price_per_sqm", Values: []any{"x"}, ID: "p01"}).Encode(): "invalid_cursor",
+	}
+	for query, code := range bad {
+		resp, err := http.Get(memTS.URL + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		var body map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body["error"] != code {
+			t.Fatalf("%s: status=%d body=%v want 400 %s", query, resp.StatusCode, body, code)
+		}
+	}
+}
+
+func TestGETProperties_MemoryInsertionOrder(t *testing.T) {
+	t.Parallel()
+
+	seed := []domain.Property{
+		{ID: "c", Title: "C", Location: "X", Price: 1},
+		{ID: "a", Title: "A", Location: "X", Price: 1},
+		{ID: "d", Title: "D", Location: "X", Price: 1},
+		{ID: "b", Title: "B", Location: "X", Price: 1},
+	}
+	ts := httptest.NewServer(NewServer(nil, seed).Routes())
+	defer ts.Close()
+
+	page := func(query string) PropertiesListResponse {
+		resp, err := http.Get(ts.URL + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status=%d", query, resp.StatusCode)
+		}
+		var got PropertiesListResponse
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		return got
+	}
+	ids := func(r PropertiesListResponse) []string {
+		out := []string{}
+		for _, it := range r.Items {
+			out = append(out, it.ID)
+		}
+		return out
+	}
+
+	// без sort и q — порядок добавления, как до курсоров; новый объект в конце
+	if code, _ := doJSON(t, http.MethodPost, ts.URL+"/properties", `{"id": "0", "title": "N", "location": "X", "price": 1}`); code != http.StatusCreated {
+		t.Fatalf("create status=%d", code)
+	}
+	if got := ids(page("limit=10")); !reflect.DeepEqual(got, []string{"c", "a", "d", "b", "0"}) {
+		t.Fatalf("offset listing: %v", got)
+	}
+
+	first := page("limit=2")
+	if got := ids(first); !reflect.DeepEqual(got, []string{"c", "a"}) || first.NextCursor == "" {
+		t.Fatalf("first page: %v next=%q", got, first.NextCursor)
+	}
+	// удалённый последний объект страницы не сдвигает следующую
+	if code, _ := doJSON(t, http.MethodDelete, ts.URL+"/properties/a", ""); code != http.StatusOK {
+		t.Fatalf("delete status=%d", code)
+	}
+	second := page("limit=2&cursor=" + url.QueryEscape(first.NextCursor))
+	if got := ids(second); !reflect.DeepEqual(got, []string{"d", "b"}) {
+		t.Fatalf("second page: %v", got)
+	}
+	if got := ids(page("limit=2&cursor=" + url.QueryEscape(second.NextCursor))); !reflect.DeepEqual(got, []string{"0"}) {
+		t.Fatalf("third page: %v", got)
+	}
+}
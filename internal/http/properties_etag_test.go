@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+func TestProperties_ETagIfMatch(t *testing.T) {
+	t.Parallel()
+
+	seed := domain.Property{ID: "es-001", Title: "A", Location: "Valencia", Price: 320000}
+	memTS := httptest.NewServer(NewServer(nil, []domain.Property{seed}).Routes())
+	defer memTS.Close()
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany([]domain.Property{seed}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	send := func(method, url, ifMatch, body string) (int, string) {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get("ETag")
+	}
+
+	for name, base := range map[string]string{"memory": memTS.URL, "sqlite": sqlTS.URL} {
+		url := base + "/properties/es-001"
+		put := `{"title": "B", "location": "Valencia", "price": 300000}`
+
+		if code, tag := send(http.MethodGet, url, "", ""); code != http.StatusOK || tag != `"1"` {
+			t.Fatalf("%s GET status=%d etag=%s", name, code, tag)
+		}
+
+		// два агента прочитали версию 1; второй должен получить 412
+		if code, tag := send(http.MethodPut, url, `"1"`, put); code != http.StatusOK || tag != `"2"` {
+			t.Fatalf("%s first PUT status=%d etag=%s", name, code, tag)
+		}
+		if code, tag := send(http.MethodPatch, url, `"1"`, `{"price": 1}`); code != http.StatusPreconditionFailed || tag != `"2"` {
+			t.Fatalf("%s stale PATCH status=%d etag=%s", name, code, tag)
+		}
+
+		if code, tag := send(http.MethodPatch, url, `"7", "2"`, `{"price": 290000}`); code != http.StatusOK || tag != `"3"` {
+			t.Fatalf("%s PATCH with tag list status=%d etag=%s", name, code, tag)
+		}
+		if code, _ := send(http.MethodPut, url, `W/"3"`, put); code != http.StatusPreconditionFailed {
+			t.Fatalf("%s weak If-Match status=%d want 412", name, code)
+		}
+		if code, tag := send(http.MethodPut, url, "*", put); code != http.StatusOK || tag != `"4"` {
+			t.Fatalf("%s If-Match * status=%d etag=%s", name, code, tag)
+		}
+		// without If-Match the edit still applies, last writer wins
+		if code, tag := send(http.MethodPatch, url, "", `{"price": 280000}`); code != http.StatusOK || tag != `"5"` {
+			t.Fatalf("%s PATCH without If-Match status=%d etag=%s", name, code, tag)
+		}
+
+		code, got := doJSON(t, http.MethodGet, url, "")
+		if code != http.StatusOK || got.Version != 5 || got.Price != 280000 || got.UpdatedAt.IsZero() {
+			t.Fatalf("%s GET got=%+v", name, got)
+		}
+	}
+}
+
+// racingRepo loses every conditional update, as if another writer always got in first.
+type racingRepo struct {
+	PropertiesRepo
+	updates int
+}
+
+func (r *racingRepo) Update(ctx context.Context, p domain.Property, ifVersion int64) (domain.Property, bool, error) {
+	r.updates++
+	return domain.Property{}, false, storage.ErrVersionConflict
+}
+
+func TestProperties_LostRaceStatus(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(nil, []domain.Property{{ID: "es-001", Title: "A", Location: "Valencia", Price: 1}})
+	repo := &racingRepo{PropertiesRepo: srv.repo()}
+	srv.PropsRepo = repo
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	// с If-Match проигранная гонка — это 412, без него — 409 после всех повторов
+	for ifMatch, want := range map[string]int{`"1"`: http.StatusPreconditionFailed, "": http.StatusConflict} {
+		repo.updates = 0
+		req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/properties/es-001", bytes.NewBufferString(`{"price": 2}`))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PATCH: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("If-Match %q: status=%d want %d", ifMatch, resp.StatusCode, want)
+		}
+		if ifMatch == "" && repo.updates != maxEditRetries+1 {
+			t.Fatalf("updates=%d want %d", repo.updates, maxEditRetries+1)
+		}
+	}
+}
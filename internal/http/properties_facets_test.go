@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+func TestGETProperties_Facets(t *testing.T) {
+	t.Parallel()
+
+	get := func(url string) (int, PropertiesListResponse) {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		defer resp.Body.Close()
+		var got PropertiesListResponse
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		return resp.StatusCode, got
+	}
+
+	e := func(v float64) *float64 { return &v }
+	want := storage.Facets{
+		Location: []storage.FacetCount{{Value: "Valencia", Count: 2}, {Value: "Alicante", Count: 1}},
+		Bedrooms: []storage.FacetCount{{Value: "2", Count: 1}, {Value: "3", Count: 2}},
+		// "Parking" и " parking " — одно удобство
+		Amenities: []storage.FacetCount{{Value: "parking", Count: 3}, {Value: "balcony", Count: 1}, {Value: "pool", Count: 1}},
+		Price: []storage.PriceBucket{
+			{Max: e(320000), Count: 1},
+			{Min: e(320000), Max: e(400000), Count: 1},
+			{Min: e(400000), Count: 1},
+		},
+	}
+
+	backends := filterBackends(t, nil)
+	results := map[string]PropertiesListResponse{}
+	for name, ts := range backends {
+		// фасеты считаются по всем подходящим объектам, а не по странице
+		code, got := get(ts.URL + "/properties?amenity=parking&limit=1&facets=location,bedrooms,amenities,price&price_buckets=320000,400000")
+		if code != http.StatusOK || got.Facets == nil {
+			t.Fatalf("%s: status=%d facets=%v", name, code, got.Facets)
+		}
+		if !reflect.DeepEqual(*got.Facets, want) {
+			t.Fatalf("%s facets=%+v\nwant %+v", name, *got.Facets, want)
+		}
+		got.NextCursor = "" // курсор memory — по порядку добавления, SQLite — по id
+		results[name] = got
+	}
+	if !reflect.DeepEqual(results["memory"], results["sqlite"]) {
+		t.Fatalf("backends differ: %+v vs %+v", results["memory"], results["sqlite"])
+	}
+
+	// бакеты по умолчанию; без facets= ключа нет
+	_, got := get(backends["sqlite"].URL + "/properties?facets=price")
+	if len(got.Facets.Price) != len(DefaultPriceBuckets)+1 || got.Facets.Location != nil {
+		t.Fatalf("default buckets: %+v", got.Facets)
+	}
+	if _, got := get(backends["memory"].URL + "/properties"); got.Facets != nil {
+		t.Fatalf("facets without facets=: %+v", got.Facets)
+	}
+
+	for _, q := range []string{"facets=color", "facets=price&price_buckets=5,3", "facets=price&price_buckets=x"} {
+		if code, _ := get(backends["memory"].URL + "/properties?" + q); code != http.StatusBadRequest {
+			t.Fatalf("%s: status=%d want 400", q, code)
+		}
+	}
+}
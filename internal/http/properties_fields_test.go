@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestGETProperties_FieldsAndFullView(t *testing.T) {
+	t.Parallel()
+
+	for name, ts := range filterBackends(t, nil) {
+		items := func(query string) []map[string]any {
+			t.Helper()
+			resp, err := http.Get(ts.URL + "/properties?" + query)
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s %s: status=%d", name, query, resp.StatusCode)
+			}
+			var got struct {
+				Total int              `json:"total"`
+				Items []map[string]any `json:"items"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&got)
+			return got.Items
+		}
+
+		// fields: только выбранные поля, id всегда, features.* — вложенным объектом
+		got := items("fields=title,features.quietness,price&sort=price&limit=1")
+		want := []map[string]any{{"id": "d", "title": "D", "price": 250000.0, "features": map[string]any{"quietness": 0.7}}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s fields: got %v want %v", name, got, want)
+		}
+		got = items("fields=features,features.quietness&limit=1")
+		if f, _ := got[0]["features"].(map[string]any); len(f) != 10 {
+			t.Fatalf("%s: whole features expected, got %v", name, got[0])
+		}
+		got = items("fields=id&location=nowhere")
+		if got == nil || len(got) != 0 {
+			t.Fatalf("%s: empty page must be [], got %v", name, got)
+		}
+
+		// view=full совпадает с GET /properties/{id}
+		resp, err := http.Get(ts.URL + "/properties?view=full&amenity=parking")
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		var full struct {
+			Items []domain.Property `json:"items"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&full)
+		resp.Body.Close()
+		if len(full.Items) != 3 {
+			t.Fatalf("%s full: %d items", name, len(full.Items))
+		}
+		for _, p := range full.Items {
+			_, one := doJSON(t, http.MethodGet, ts.URL+"/properties/"+p.ID, "")
+			if !reflect.DeepEqual(p, one) {
+				t.Fatalf("%s full %s:\n got %+v\nwant %+v", name, p.ID, p, one)
+			}
+		}
+
+		for query, code := range map[string]string{
+			"fields=title,nope":       "invalid_fields",
+			"fields=features.nope":    "invalid_fields",
+			"fields=":                 "invalid_fields",
+			"view=compact":            "invalid_view",
+			"view=full&fields=secret": "invalid_fields",
+		} {
+			resp, err := http.Get(ts.URL + "/properties?" + query)
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			var body map[string]string
+			_ = json.NewDecoder(resp.Body).Decode(&body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest || body["error"] != code {
+				t.Fatalf("%s %s: status=%d body=%v want 400 %s", name, query, resp.StatusCode, body, code)
+			}
+		}
+	}
+}
+
+func TestGETProperties_FieldsSnippet(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(NewServer(nil, []domain.Property{
+		{ID: "a", Title: "Sea view flat", Location: "X", Description: "quiet street"},
+	}).Routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/properties?q=sea&fields=snippet")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	var got struct {
+		Items []map[string]any `json:"items"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	want := []map[string]any{{"id": "a", "snippet": "<mark>Sea</mark> view flat"}}
+	if !reflect.DeepEqual(got.Items, want) {
+		t.Fatalf("got %v want %v", got.Items, want)
+	}
+}
@@ -0,0 +1,139 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/matching"
+)
+
+var filterSeed = []domain.Property{
+	{ID: "a", Title: "A", Location: "Valencia", Price: 300000, Bedrooms: 2, Amenities: []string{"Parking", "pool"},
+		Features: domain.Features{Quietness: 0.8, DistanceToSeaKm: 1.5}},
+	{ID: "b", Title: "B", Location: "Valencia", Price: 350000, Bedrooms: 3, Amenities: []string{"parking"},
+		Features: domain.Features{Quietness: 0.5, DistanceToSeaKm: 0.5}},
+	{ID: "c", Title: "C", Location: "Alicante", Price: 400000, Bedrooms: 3, Amenities: []string{" parking ", "balcony"},
+		Features: domain.Features{Quietness: 0.9, DistanceToSeaKm: 4}},
+	{ID: "d", Title: "D", Location: "Alicante", Price: 250000, Bedrooms: 1,
+		Features: domain.Features{Quietness: 0.7, DistanceToSeaKm: 0.2}},
+}
+
+// filterBackends returns a memory and an SQLite server holding filterSeed.
+func filterBackends(t *testing.T, engine *matching.Engine) map[string]*httptest.Server {
+	t.Helper()
+
+	memTS := httptest.NewServer(NewServer(engine, filterSeed).Routes())
+	t.Cleanup(memTS.Close)
+
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany(filterSeed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if engine != nil {
+		srv := NewServer(engine, nil)
+		srv.PropsRepo = &SQLitePropertiesRepo{Store: store}
+		sqlTS = httptest.NewServer(srv.Routes())
+		t.Cleanup(sqlTS.Close)
+	}
+	return map[string]*httptest.Server{"memory": memTS, "sqlite": sqlTS}
+}
+
+func TestGETProperties_FeatureAndAmenityFilters(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"amenity=parking", []string{"a", "b", "c"}},
+		{"amenity=PARKING&amenity=pool", []string{"a"}},
+		{"amenity=parking&min_quietness=0.6&max_sea_km=2", []string{"a"}},
+		{"min_quietness=0.7", []string{"a", "c", "d"}},
+		{"max_distance_to_sea_km=0.5&location=alicante", []string{"d"}},
+		{"min_quietness=0.5&max_quietness=0.5", []string{"b"}},
+		{"amenity=sauna", []string{}},
+	}
+
+	for name, ts := range filterBackends(t, nil) {
+		for _, tc := range cases {
+			resp, err := http.Get(ts.URL + "/properties?" + tc.query)
+			if err != nil {
+				t.Fatalf("%s GET: %v", name, err)
+			}
+			var got PropertiesListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&got)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s %s: status=%d", name, tc.query, resp.StatusCode)
+			}
+
+			ids := []string{}
+			for _, it := range got.Items {
+				ids = append(ids, it.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.want) || got.Total != len(tc.want) {
+				t.Fatalf("%s %s: ids=%v total=%d want %v", name, tc.query, ids, got.Total, tc.want)
+			}
+		}
+	}
+}
+
+func TestGETProperties_FeatureFilterValidation(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(nil, nil)
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	cases := map[string]string{
+		"min_quietness=x":                     "invalid_min_quietness",
+		"max_sea_km=-1":                       "invalid_max_sea_km",
+		"min_walkability=NaN":                 "invalid_min_walkability",
+		"min_quietness=0.8&max_quietness=0.2": "min_quietness_gt_max_quietness",
+	}
+	for query, code := range cases {
+		resp, err := http.Get(ts.URL + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		var body map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body["error"] != code {
+			t.Fatalf("%s: status=%d body=%v want 400 %s", query, resp.StatusCode, body, code)
+		}
+	}
+}
+
+// SQL-prefiltered /match must return what the engine alone returns in memory.
+func TestPOSTMatch_HardFiltersPushedToSQL(t *testing.T) {
+	t.Parallel()
+
+	backends := filterBackends(t, matching.NewEngine(matching.DefaultWeights()))
+	body := `{"profile": {"priorities": {"quietness": 1}, "hard_filters": {
+		"must_have_amenities": ["parking"], "excluded_amenities": ["Balcony"],
+		"max_distance_to_sea_km": 2, "min_features": {"Quietness": 0.4},
+		"allowed_locations": ["valencia", "alicante"], "blocked_locations": ["madrid"]}}, "limit": 10}`
+
+	results := map[string][]string{}
+	for name, ts := range backends {
+		resp, err := http.Post(ts.URL+"/match", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("%s POST /match: %v", name, err)
+		}
+		var got MatchResponse
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		for _, r := range got.Results {
+			results[name] = append(results[name], r.Property.ID)
+		}
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(results["memory"], want) || !reflect.DeepEqual(results["sqlite"], want) {
+		t.Fatalf("results=%v want %v on both backends", results, want)
+	}
+}
@@ -0,0 +1,134 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestGETProperties_GeoFiltersAndDistanceSort(t *testing.T) {
+	t.Parallel()
+
+	at := func(lat, lon float64) *domain.GeoPoint { return &domain.GeoPoint{Lat: lat, Lon: lon} }
+	// в порядке id: без sort memory отдаёт порядок добавления, SQLite — по id
+	seed := []domain.Property{
+		{ID: "alicante", Title: "A", Location: "Alicante", Price: 1, Coordinates: at(38.3452, -0.4810)},
+		{ID: "fiji", Title: "F", Location: "Suva", Price: 1, Coordinates: at(-18.1416, 178.4419)},
+		{ID: "madrid", Title: "M", Location: "Madrid", Price: 1, Coordinates: at(40.4168, -3.7038)},
+		{ID: "nowhere", Title: "N", Location: "Valencia", Price: 1},
+		{ID: "samoa", Title: "S", Location: "Apia", Price: 1, Coordinates: at(-13.8333, -171.7500)},
+		{ID: "valencia", Title: "V", Location: "Valencia", Price: 1, Coordinates: at(39.4699, -0.3763)},
+	}
+	memTS := httptest.NewServer(NewServer(nil, seed).Routes())
+	defer memTS.Close()
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany(seed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	backends := map[string]*httptest.Server{"memory": memTS, "sqlite": sqlTS}
+
+	list := func(ts *httptest.Server, query string) (int, PropertiesListResponse) {
+		resp, err := http.Get(ts.URL + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		defer resp.Body.Close()
+		var got PropertiesListResponse
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		return resp.StatusCode, got
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"near=39.47,-0.38&radius_km=200", []string{"alicante", "valencia"}},
+		{"near=39.47,-0.38&radius_km=400&sort=distance", []string{"valencia", "alicante", "madrid"}},
+		{"near=39.47,-0.38&sort=-distance", []string{"fiji", "samoa", "madrid", "alicante", "valencia", "nowhere"}},
+		{"bbox=38,-1,40,0", []string{"alicante", "valencia"}},
+		{"bbox=-20,170,-10,-170", []string{"fiji", "samoa"}}, // через антимеридиан
+		{"near=-16,179&radius_km=1200&sort=distance", []string{"fiji", "samoa"}},
+		{"bbox=38,-4,41,0&location=valencia", []string{"valencia"}},
+	}
+	for _, tc := range cases {
+		for name, ts := range backends {
+			code, got := list(ts, tc.query)
+			if code != http.StatusOK {
+				t.Fatalf("%s %s: status=%d", name, tc.query, code)
+			}
+			ids := []string{}
+			for _, it := range got.Items {
+				ids = append(ids, it.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.want) || got.Total != len(tc.want) {
+				t.Fatalf("%s %s: got %v (total %d), want %v", name, tc.query, ids, got.Total, tc.want)
+			}
+		}
+	}
+
+	// расстояние в ответе, курсор по расстоянию совпадает в обоих режимах
+	for name, ts := range backends {
+		_, first := list(ts, "near=39.47,-0.38&sort=distance&limit=2")
+		if d := first.Items[0].DistanceKm; d == nil || *d > 1 || first.Items[0].Coordinates == nil {
+			t.Fatalf("%s: first item %+v", name, first.Items[0])
+		}
+		_, second := list(ts, "near=39.47,-0.38&sort=distance&limit=2&cursor="+url.QueryEscape(first.NextCursor))
+		if len(second.Items) != 2 || second.Items[0].ID != "madrid" || second.Items[1].ID != "samoa" {
+			t.Fatalf("%s: second page %+v", name, second.Items)
+		}
+		// курсор от другой точки не подходит
+		code, _ := list(ts, "near=40,-3&sort=distance&limit=2&cursor="+url.QueryEscape(first.NextCursor))
+		if code != http.StatusBadRequest {
+			t.Fatalf("%s: cursor for another origin: status=%d", name, code)
+		}
+	}
+
+	// координаты меняются через PATCH, и поиск это видит
+	for name, ts := range backends {
+		code, _ := doJSON(t, http.MethodPatch, ts.URL+"/properties/nowhere", `{"coordinates": {"lat": 39.48, "lon": -0.37}}`)
+		if code != http.StatusOK {
+			t.Fatalf("%s patch: status=%d", name, code)
+		}
+		if _, got := list(ts, "bbox=39.4,-0.4,39.5,-0.3"); got.Total != 2 {
+			t.Fatalf("%s: after patch total=%d", name, got.Total)
+		}
+		if code, _ := doJSON(t, http.MethodPatch, ts.URL+"/properties/nowhere", `{"coordinates": null}`); code != http.StatusOK {
+			t.Fatalf("%s patch null: status=%d", name, code)
+		}
+		if _, got := list(ts, "bbox=39.4,-0.4,39.5,-0.3"); got.Total != 1 {
+			t.Fatalf("%s: after clearing total=%d", name, got.Total)
+		}
+	}
+
+	if code, _ := doJSON(t, http.MethodPost, memTS.URL+"/properties", `{"title": "t", "location": "x", "price": 1, "coordinates": {"lat": 91, "lon": 0}}`); code != http.StatusBadRequest {
+		t.Fatalf("create with lat 91: status=%d", code)
+	}
+	for query, want := range map[string]string{
+		"near=39.47":                    "invalid_near",
+		"near=95,0":                     "invalid_near",
+		"near=a,b":                      "invalid_near",
+		"radius_km=10":                  "radius_km_without_near",
+		"near=1,1&radius_km=-1":         "invalid_radius_km",
+		"bbox=1,2,3":                    "invalid_bbox",
+		"bbox=40,0,38,1":                "invalid_bbox",
+		"bbox=0,0,10,190":               "invalid_bbox",
+		"sort=distance":                 "distance_sort_without_near",
+		"sort=price,-distance&near=1,x": "invalid_near",
+	} {
+		resp, err := http.Get(memTS.URL + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		var body map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body["error"] != want {
+			t.Fatalf("%s: status=%d body=%v want 400 %s", query, resp.StatusCode, body, want)
+		}
+	}
+}
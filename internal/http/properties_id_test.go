@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+func TestProperties_IDs(t *testing.T) {
+	t.Parallel()
+
+	seed := domain.Property{ID: "es-001", Title: "A", Location: "Valencia", Price: 320000}
+	memTS := httptest.NewServer(NewServer(nil, []domain.Property{seed}).Routes())
+	defer memTS.Close()
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany([]domain.Property{seed}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	for name, base := range map[string]string{"memory": memTS.URL, "sqlite": sqlTS.URL} {
+		create := func(body string) (int, string) {
+			code, p := doJSON(t, http.MethodPost, base+"/properties", body)
+			return code, p.ID
+		}
+		body := `{"title": "N", "location": "Valencia", "price": 1}`
+
+		// удалили средний — следующий id не должен совпасть ни с одним выданным
+		var ids []string
+		for i := 0; i < 3; i++ {
+			_, id := create(body)
+			ids = append(ids, id)
+		}
+		doJSON(t, http.MethodDelete, base+"/properties/"+ids[1], "")
+		_, id := create(body)
+		ids = append(ids, id)
+		for i, id := range ids {
+			if !strings.HasPrefix(id, storage.PropertyIDPrefix) || len(id) != len(storage.PropertyIDPrefix)+26 {
+				t.Fatalf("%s id=%q is not p-<ULID>", name, id)
+			}
+			if i > 0 && id <= ids[i-1] {
+				t.Fatalf("%s ids not increasing: %v", name, ids)
+			}
+		}
+
+		// seed id is kept; external ids are accepted once
+		if code, _ := doJSON(t, http.MethodGet, base+"/properties/es-001", ""); code != http.StatusOK {
+			t.Fatalf("%s seed GET status=%d", name, code)
+		}
+		if code, id := create(`{"id": "crm-42", "title": "E", "location": "Valencia", "price": 1}`); code != http.StatusCreated || id != "crm-42" {
+			t.Fatalf("%s external id status=%d id=%q", name, code, id)
+		}
+		for _, dup := range []string{"crm-42", "es-001"} {
+			if code, _ := create(`{"id": "` + dup + `", "title": "E", "location": "Valencia", "price": 1}`); code != http.StatusConflict {
+				t.Fatalf("%s duplicate %s status=%d want 409", name, dup, code)
+			}
+		}
+		for _, bad := range []string{"a/b", "..", "with space", strings.Repeat("x", 65)} {
+			if code, _ := create(`{"id": "` + bad + `", "title": "E", "location": "Valencia", "price": 1}`); code != http.StatusBadRequest {
+				t.Fatalf("%s id %q status=%d want 400", name, bad, code)
+			}
+		}
+	}
+}
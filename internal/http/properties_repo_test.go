@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+func newSQLiteServer(t *testing.T) (*httptest.Server, *storage.SQLiteStore) {
+	t.Helper()
+
+	store, err := storage.OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if err := store.EnsureSchema(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	srv := NewServer(nil, nil)
+	srv.PropsRepo = &SQLitePropertiesRepo{Store: store}
+	ts := httptest.NewServer(srv.Routes())
+	t.Cleanup(ts.Close)
+	return ts, store
+}
+
+func TestProperties_SQLiteCRUD(t *testing.T) {
+	t.Parallel()
+
+	ts, store := newSQLiteServer(t)
+
+	body := `{"title": "A", "location": "Valencia", "price": 320000, "bedrooms": 3, "amenities": ["parking"]}`
+	resp, err := http.Post(ts.URL+"/properties", "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	var created domain.Property
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || created.ID == "" {
+		t.Fatalf("POST status=%d id=%q", resp.StatusCode, created.ID)
+	}
+
+	// записано в БД, а не в Server.Properties
+	if _, ok, err := store.GetProperty(created.ID); err != nil || !ok {
+		t.Fatalf("store.GetProperty ok=%v err=%v", ok, err)
+	}
+
+	resp, err = http.Get(ts.URL + "/properties/" + created.ID)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	var got domain.Property
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || got.Title != "A" || len(got.Amenities) != 1 {
+		t.Fatalf("GET status=%d got=%+v", resp.StatusCode, got)
+	}
+
+	del := func() int {
+		req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/properties/"+created.ID, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("DELETE: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := del(); code != http.StatusOK {
+		t.Fatalf("DELETE status=%d", code)
+	}
+	if code := del(); code != http.StatusNotFound {
+		t.Fatalf("second DELETE status=%d", code)
+	}
+	if n, _ := store.CountProperties(); n != 0 {
+		t.Fatalf("count=%d after delete", n)
+	}
+}
+
+func TestProperties_SQLiteErrorsAre500(t *testing.T) {
+	t.Parallel()
+
+	ts, store := newSQLiteServer(t)
+	_ = store.Close()
+
+	reqs := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/properties", ""},
+		{http.MethodGet, "/properties/es-001", ""},
+		{http.MethodDelete, "/properties/es-001", ""},
+		{http.MethodPost, "/properties", `{"title": "A", "location": "Valencia", "price": 1}`},
+	}
+	for _, rq := range reqs {
+		req, _ := http.NewRequest(rq.method, ts.URL+rq.path, bytes.NewBufferString(rq.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", rq.method, rq.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("%s %s status=%d want 500", rq.method, rq.path, resp.StatusCode)
+		}
+	}
+}
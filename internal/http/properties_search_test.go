@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestGETProperties_TextSearch(t *testing.T) {
+	t.Parallel()
+
+	seed := []domain.Property{
+		{ID: "a", Title: "Flat with terrace", Location: "Valencia", Price: 300000,
+			Description: "Renovated flat, big terrace & sea view."},
+		{ID: "b", Title: "Sea view house", Location: "Alicante", Price: 500000,
+			Description: "Quiet street. The terrace faces the sea; sea view from every room."},
+		{ID: "c", Title: "Loft", Location: "Valencia", Price: 250000, Description: "Renovated in 2023."},
+	}
+	memTS := httptest.NewServer(NewServer(nil, seed).Routes())
+	defer memTS.Close()
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany(seed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	list := func(base, query string) (int, PropertiesListResponse) {
+		resp, err := http.Get(base + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		defer resp.Body.Close()
+		var got PropertiesListResponse
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		return resp.StatusCode, got
+	}
+
+	cases := []struct {
+		q    string
+		sort string
+		want []string
+	}{
+		{"terrace", "", []string{"a", "b"}}, // title hit outranks description hits
+		{"sea view", "", []string{"b", "a"}},
+		{"RENOVATED", "", []string{"a", "c"}},
+		{"renovated", "price_asc", []string{"c", "a"}},
+		{"sauna", "", []string{}},
+	}
+	for _, tc := range cases {
+		query := "q=" + url.QueryEscape(tc.q)
+		if tc.sort != "" {
+			query += "&sort=" + tc.sort
+		}
+		_, mem := list(memTS.URL, query)
+		_, sql := list(sqlTS.URL, query)
+
+		ids := []string{}
+		for _, it := range mem.Items {
+			ids = append(ids, it.ID)
+		}
+		if !reflect.DeepEqual(ids, tc.want) || mem.Total != len(tc.want) {
+			t.Fatalf("q=%q: ids=%v total=%d want %v", tc.q, ids, mem.Total, tc.want)
+		}
+		// оба бэкенда отдают одно и то же, включая сниппеты
+		if !reflect.DeepEqual(mem, sql) {
+			t.Fatalf("q=%q: memory=%+v sqlite=%+v", tc.q, mem, sql)
+		}
+	}
+
+	_, got := list(memTS.URL, "q=sea+view")
+	if got.Items[0].Snippet != "Quiet street. The terrace faces the <mark>sea</mark>; <mark>sea</mark> <mark>view</mark> from every room." {
+		t.Fatalf("snippet=%q", got.Items[0].Snippet)
+	}
+	if got.Items[1].Snippet != "Renovated flat, big terrace &amp; <mark>sea</mark> <mark>view</mark>." {
+		t.Fatalf("escaped snippet=%q", got.Items[1].Snippet)
+	}
+
+	if code, _ := list(memTS.URL, "q=%21%21"); code != http.StatusBadRequest {
+		t.Fatalf("q=!! status=%d want 400", code)
+	}
+}
@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestGETProperties_ExtendedFiltersAndSort(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	seed := []domain.Property{
+		{ID: "a", Title: "A", Location: "X", Price: 300000, Bedrooms: 2, Bathrooms: 1, AreaSQM: 100,
+			Amenities: []string{"pool"}, CreatedAt: day(3)}, // 3000/m²
+		{ID: "b", Title: "B", Location: "X", Price: 300000, Bedrooms: 3, Bathrooms: 2, AreaSQM: 150,
+			Amenities: []string{"parking"}, CreatedAt: day(1)}, // 2000/m²
+		{ID: "c", Title: "C", Location: "X", Price: 400000, Bedrooms: 3, Bathrooms: 2, AreaSQM: 200,
+			Amenities: []string{"pool", "parking"}, CreatedAt: day(2)}, // 2000/m²
+		{ID: "d", Title: "D", Location: "X", Price: 250000, Bedrooms: 4, Bathrooms: 3, CreatedAt: day(4)}, // без площади
+	}
+	memTS := httptest.NewServer(NewServer(nil, seed).Routes())
+	defer memTS.Close()
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany(seed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"max_bedrooms=3&min_bathrooms=2", []string{"b", "c"}},
+		{"max_bathrooms=1", []string{"a"}},
+		{"min_area_sqm=120&max_area_sqm=180", []string{"b"}},
+		{"amenities=pool,parking", []string{"c"}},
+		{"amenities=pool,parking&amenities_match=any&sort=-price", []string{"c", "a", "b"}},
+		{"amenity=pool&amenity=sauna&amenities_match=any", []string{"a", "c"}},
+		{"max_price_per_sqm=2500", []string{"b", "c"}},
+		{"min_price_per_sqm=2500", []string{"a"}},
+		// без площади цены за м² нет — такие объекты в конце в обе стороны
+		{"sort=price_per_sqm,-area", []string{"c", "b", "a", "d"}},
+		{"sort=-price_per_sqm,-area", []string{"a", "c", "b", "d"}},
+		{"sort=-bedrooms,price", []string{"d", "b", "c", "a"}},
+		{"sort=created_at", []string{"b", "c", "a", "d"}},
+		{"sort=-created_at&limit=2&offset=1", []string{"a", "c"}},
+		{"sort=price_asc", []string{"d", "a", "b", "c"}}, // равные цены — по id
+	}
+	for _, tc := range cases {
+		for name, ts := range map[string]*httptest.Server{"memory": memTS, "sqlite": sqlTS} {
+			resp, err := http.Get(ts.URL + "/properties?" + tc.query)
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			var got PropertiesListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&got)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s %s: status=%d", name, tc.query, resp.StatusCode)
+			}
+			ids := []string{}
+			for _, it := range got.Items {
+				ids = append(ids, it.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.want) {
+				t.Fatalf("%s %s: ids=%v want %v", name, tc.query, ids, tc.want)
+			}
+		}
+	}
+
+	bad := map[string]string{
+		"sort=size":                           "invalid_sort",
+		"sort=price,-price":                   "invalid_sort",
+		"sort=price,":                         "invalid_sort",
+		"max_bedrooms=two":                    "invalid_max_bedrooms",
+		"min_bathrooms=-1":                    "invalid_min_bathrooms",
+		"min_area_sqm=200&max_area_sqm=100":   "min_area_sqm_gt_max_area_sqm",
+		"max_price_per_sqm=Inf":               "invalid_max_price_per_sqm",
+		"amenities=pool&amenities_match=some": "invalid_amenities_match",
+		"min_price=500000&max_price=100000":   "min_price_gt_max_price",
+	}
+	for query, code := range bad {
+		resp, err := http.Get(memTS.URL + "/properties?" + query)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		var body map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body["error"] != code {
+			t.Fatalf("%s: status=%d body=%v want 400 %s", query, resp.StatusCode, body, code)
+		}
+	}
+}
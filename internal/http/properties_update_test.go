@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func doJSON(t *testing.T, method, url, body string) (int, domain.Property) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	var p domain.Property
+	if resp.StatusCode < 300 {
+		_ = json.NewDecoder(resp.Body).Decode(&p)
+	}
+	return resp.StatusCode, p
+}
+
+func TestProperties_PutPatch(t *testing.T) {
+	t.Parallel()
+
+	seed := domain.Property{
+		ID: "es-001", Title: "A", Location: "Valencia", Price: 320000, Bedrooms: 3,
+		Description: "old", Amenities: []string{"parking", "balcony"},
+		Features:  domain.Features{Quietness: 0.6, SunExposure: 0.8},
+		CreatedAt: time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	memTS := httptest.NewServer(NewServer(nil, []domain.Property{seed}).Routes())
+	defer memTS.Close()
+	sqlTS, store := newSQLiteServer(t)
+	if err := store.UpsertMany([]domain.Property{seed}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	for name, base := range map[string]string{"memory": memTS.URL, "sqlite": sqlTS.URL} {
+		url := base + "/properties/es-001"
+
+		// PATCH: объекты сливаются, null удаляет поле, остальное заменяется
+		code, got := doJSON(t, http.MethodPatch, url, `{"price": 299000, "description": null, "amenities": ["pool"], "features": {"quietness": 0.9}}`)
+		if code != http.StatusOK {
+			t.Fatalf("%s PATCH status=%d", name, code)
+		}
+		if got.ID != "es-001" || got.Price != 299000 || got.Description != "" || got.Title != "A" ||
+			len(got.Amenities) != 1 || got.Features.Quietness != 0.9 || got.Features.SunExposure != 0.8 {
+			t.Fatalf("%s PATCH got=%+v", name, got)
+		}
+
+		// PUT заменяет объект целиком, id сохраняется
+		code, got = doJSON(t, http.MethodPut, url, `{"title": "B", "location": "Alicante", "price": 250000}`)
+		if code != http.StatusOK || got.ID != "es-001" || got.Title != "B" || got.Bedrooms != 0 || len(got.Amenities) != 0 {
+			t.Fatalf("%s PUT status=%d got=%+v", name, code, got)
+		}
+		if code, got = doJSON(t, http.MethodGet, url, ""); code != http.StatusOK || got.Location != "Alicante" || got.Features.SunExposure != 0 ||
+			!got.CreatedAt.Equal(seed.CreatedAt) {
+			t.Fatalf("%s GET after PUT status=%d got=%+v", name, code, got)
+		}
+
+		// validation is the same as create
+		for _, tc := range []struct{ method, body string }{
+			{http.MethodPut, `{"title": "B", "location": "Alicante"}`},
+			{http.MethodPatch, `{"title": null}`},
+			{http.MethodPatch, `{"price": -1}`},
+			{http.MethodPatch, `{"price": "cheap"}`},
+			{http.MethodPatch, `[1]`},
+			{http.MethodPut, `{`},
+		} {
+			if code, _ := doJSON(t, tc.method, url, tc.body); code != http.StatusBadRequest {
+				t.Fatalf("%s %s %s status=%d want 400", name, tc.method, tc.body, code)
+			}
+		}
+
+		for _, method := range []string{http.MethodPut, http.MethodPatch} {
+			if code, _ := doJSON(t, method, base+"/properties/missing", `{"title": "B", "location": "Alicante", "price": 1}`); code != http.StatusNotFound {
+				t.Fatalf("%s %s missing status=%d", name, method, code)
+			}
+		}
+	}
+}
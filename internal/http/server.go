@@ -1,36 +1,48 @@
 package httpapi
 
 import (
-        "context"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
 	"github.com/denisok6893-rgb/ai-property-matching/internal/matching"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
 )
 
 type Server struct {
 	Engine     *matching.Engine
-	Properties []domain.Property
-        PropsRepo  PropertiesRepo
+	PropsRepo  PropertiesRepo
+	Catalog    Catalog // what /match scores; PropsRepo when nil
+	AdminToken string  // /admin/* requires "Authorization: Bearer <token>"; unset: 403
+
+	PriceBuckets []float64 // price facet edges for GET /properties; DefaultPriceBuckets when nil
 }
 
 func NewServer(engine *matching.Engine, properties []domain.Property) *Server {
-    s := &Server{Engine: engine, Properties: properties}
-    s.PropsRepo = &InMemoryPropertiesRepo{S: s}
-    return s
+	s := &Server{Engine: engine}
+	s.PropsRepo = &InMemoryPropertiesRepo{Store: storage.NewMemoryStore(properties)}
+	return s
 }
 
 func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/match", s.handleMatch)
+	mux.HandleFunc("/match/explain", s.handleMatchExplain)
 	mux.HandleFunc("/demo", s.handleDemo)
 	mux.HandleFunc("/properties", s.handlePropertiesList)
 	mux.HandleFunc("/properties/", s.handlePropertiesGetByID)
+	mux.HandleFunc("/admin/weights", s.handleAdminWeights)
 	return mux
 }
 
@@ -40,12 +52,25 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 type MatchRequest struct {
-	Profile domain.ClientProfile `json:"profile"`
-	Limit   int                  `json:"limit"`
+	Profile           domain.ClientProfile `json:"profile"`
+	Limit             int                  `json:"limit"`
+	ExplainRejections bool                 `json:"explain_rejections"`
+	// WeightsPreset selects a named preset instead of the global weights;
+	// WeightsOverride is a partial weights object applied on top.
+	WeightsPreset   string          `json:"weights_preset,omitempty"`
+	WeightsOverride json.RawMessage `json:"weights_override,omitempty"`
+	// Lang selects the reasons language (en|ru|es); defaults to Accept-Language.
+	Lang string `json:"lang,omitempty"`
 }
 
 type MatchResponse struct {
-	Results []domain.ScoreResult `json:"results"`
+	Results         []domain.ScoreResult `json:"results"`
+	WeightsPreset   string               `json:"weights_preset,omitempty"`
+	WeightsVersion  int64                `json:"weights_version,omitempty"` // set when the global weights were used as is
+	Weights         matching.Weights     `json:"weights"`
+	Rejections      []domain.Rejection   `json:"rejections,omitempty"`
+	RejectionCounts map[string]int       `json:"rejection_counts,omitempty"`
+	Suggestions     []domain.Relaxation  `json:"suggestions,omitempty"`
 }
 
 func (s *Server) handleMatch(w http.ResponseWriter, r *http.Request) {
@@ -59,6 +84,10 @@ func (s *Server) handleMatch(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid JSON", http.StatusBadRequest)
 		return
 	}
+	if key := unknownFeature(req.Profile.HardFilters); key != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown_feature", "detail": key})
+		return
+	}
 
 	limit := req.Limit
 	if v := r.URL.Query().Get("limit"); v != "" {
@@ -70,15 +99,155 @@ func (s *Server) handleMatch(w http.ResponseWriter, r *http.Request) {
 		limit = 5
 	}
 
-	results := s.Engine.ScoreProperties(req.Profile, s.Properties, limit)
+	// Pin the weights for the whole request; admin updates may land meanwhile.
+	engine, err := s.requestEngine(r, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := MatchResponse{
+		WeightsPreset:  req.WeightsPreset,
+		WeightsVersion: engine.Version().Version,
+		Weights:        engine.Weights(),
+	}
+	// Rejections need every property, so the catalog may prefilter only without them.
+	var prefilter *domain.ClientProfile
+	if !req.ExplainRejections {
+		prefilter = &req.Profile
+	}
+	catalog := s.matchCatalog()
+	var scanErr error
+	resp.Results, resp.Rejections = engine.ScoreSeq(req.Profile, catalogSeq(r.Context(), catalog, prefilter, &scanErr), limit, req.ExplainRejections)
+	if req.ExplainRejections {
+		resp.RejectionCounts = matching.RejectionCounts(resp.Rejections)
+	}
+	if scanErr == nil && len(resp.Results) < limit {
+		resp.Suggestions = engine.SuggestRelaxationsSeq(req.Profile, catalogSeq(r.Context(), catalog, nil, &scanErr), limit)
+	}
+	if scanErr != nil {
+		http.Error(w, "failed to load properties", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(MatchResponse{Results: results}); err != nil {
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
 
-// ---- Properties API (read-only v1) ----
+type MatchExplainRequest struct {
+	Profile         domain.ClientProfile `json:"profile"`
+	PropertyID      string               `json:"property_id"`
+	WeightsPreset   string               `json:"weights_preset,omitempty"`
+	WeightsOverride json.RawMessage      `json:"weights_override,omitempty"`
+	Lang            string               `json:"lang,omitempty"`
+}
+
+type MatchExplainResponse struct {
+	domain.ScoreBreakdown
+	WeightsPreset  string           `json:"weights_preset,omitempty"`
+	WeightsVersion int64            `json:"weights_version,omitempty"`
+	Weights        matching.Weights `json:"weights"`
+}
+
+// handleMatchExplain returns the full per-factor breakdown for one property.
+func (s *Server) handleMatchExplain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MatchExplainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.PropertyID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing_property_id"})
+		return
+	}
+	if key := unknownFeature(req.Profile.HardFilters); key != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown_feature", "detail": key})
+		return
+	}
+
+	engine, err := s.requestEngine(r, MatchRequest{
+		WeightsPreset:   req.WeightsPreset,
+		WeightsOverride: req.WeightsOverride,
+		Lang:            req.Lang,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p, ok, err := s.catalog().Get(r.Context(), req.PropertyID)
+	if err != nil {
+		http.Error(w, "failed to load property", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, MatchExplainResponse{
+		ScoreBreakdown: engine.Explain(req.Profile, p),
+		WeightsPreset:  req.WeightsPreset,
+		WeightsVersion: engine.Version().Version,
+		Weights:        engine.Weights(),
+	})
+}
+
+// unknownFeature returns the first min_features key, in sorted order, that
+// names no feature; such a minimum would reject every property.
+func unknownFeature(hf domain.HardFilters) string {
+	keys := make([]string, 0, len(hf.MinFeatures))
+	for k := range hf.MinFeatures {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := domain.FeatureValue(domain.Features{}, strings.ToLower(strings.TrimSpace(k))); !ok {
+			return k
+		}
+	}
+	return ""
+}
+
+// requestEngine pins the current weights and applies the request's language,
+// weights preset and inline override, if any.
+func (s *Server) requestEngine(r *http.Request, req MatchRequest) (*matching.Engine, error) {
+	lang := req.Lang
+	if lang == "" {
+		lang = matching.NegotiateLang(r.Header.Get("Accept-Language"))
+	} else if !matching.IsSupportedLang(lang) {
+		return nil, fmt.Errorf("unsupported lang %q (want en|ru|es)", lang)
+	}
+
+	base := s.Engine.WithLang(lang)
+	if req.WeightsPreset == "" && len(req.WeightsOverride) == 0 {
+		return base, nil
+	}
+
+	w := base.Weights()
+	if req.WeightsPreset != "" {
+		preset, ok := base.Preset(req.WeightsPreset)
+		if !ok {
+			return nil, fmt.Errorf("unknown weights_preset %q", req.WeightsPreset)
+		}
+		w = preset
+	}
+	if len(req.WeightsOverride) > 0 {
+		var err error
+		if w, err = matching.ApplyOverride(w, req.WeightsOverride); err != nil {
+			return nil, fmt.Errorf("invalid weights_override: %v", err)
+		}
+	}
+	return base.WithWeights(w), nil
+}
+
+// ---- Properties API ----
 
 type PropertySummary struct {
 	ID        string   `json:"id"`
@@ -89,6 +258,10 @@ type PropertySummary struct {
 	Bathrooms int      `json:"bathrooms"`
 	AreaSQM   float64  `json:"area_sqm"`
 	Amenities []string `json:"amenities,omitempty"`
+	Snippet   string   `json:"snippet,omitempty"` // q= only: HTML with <mark> around hits
+
+	Coordinates *domain.GeoPoint `json:"coordinates,omitempty"`
+	DistanceKm  *float64         `json:"distance_km,omitempty"` // near= only
 }
 
 type PropertiesListResponse struct {
@@ -96,6 +269,10 @@ type PropertiesListResponse struct {
 	Offset int               `json:"offset"`
 	Total  int               `json:"total"`
 	Items  []PropertySummary `json:"items"`
+	Facets *storage.Facets   `json:"facets,omitempty"` // only with facets=
+
+	// NextCursor continues after the last item in either mode; empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func (s *Server) handlePropertiesList(w http.ResponseWriter, r *http.Request) {
@@ -108,99 +285,190 @@ func (s *Server) handlePropertiesList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-        q := r.URL.Query()
+	q := r.URL.Query()
 
-        // strict limit/offset validation
-        limit := 20
-        if v := q.Get("limit"); v != "" {
-            n, err := strconv.Atoi(v)
-            if err != nil || n <= 0 {
-                writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_limit"})
-                return
-            }
-            limit = n
-        }
+	// strict limit/offset validation
+	limit := 20
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_limit"})
+			return
+		}
+		limit = n
+	}
 
-        offset := 0
-        if v := q.Get("offset"); v != "" {
-            n, err := strconv.Atoi(v)
-            if err != nil || n < 0 {
-                writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_offset"})
-                return
-            }
-            offset = n
-        }
+	offset := 0
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_offset"})
+			return
+		}
+		offset = n
+	}
 
-        // strict filters validation (accept empty = not set)
-        location := q.Get("location")
+	// strict filters validation (accept empty = not set)
+	location := q.Get("location")
 
-        minPriceStr := q.Get("min_price")
-        maxPriceStr := q.Get("max_price")
-        minBedroomsStr := q.Get("min_bedrooms")
-        sortBy := q.Get("sort")
+	sortKeys, err := storage.ParseSort(q.Get("sort"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_sort"})
+		return
+	}
 
-        if sortBy != "" && sortBy != "price_asc" && sortBy != "price_desc" {
-            writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_sort"})
-            return
-        }
+	var filter storage.PropertyFilter
+	for _, code := range []string{
+		parseRange(q, "price", parseFloatParam, &filter.MinPrice, &filter.MaxPrice),
+		parseRange(q, "bedrooms", strconv.Atoi, &filter.MinBedrooms, &filter.MaxBedrooms),
+		parseRange(q, "bathrooms", strconv.Atoi, &filter.MinBathrooms, &filter.MaxBathrooms),
+		parseRange(q, "area_sqm", parseFloatParam, &filter.MinAreaSQM, &filter.MaxAreaSQM),
+		parseRange(q, "price_per_sqm", parseFloatParam, &filter.MinPricePerSQM, &filter.MaxPricePerSQM),
+	} {
+		if code != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+			return
+		}
+	}
 
-        var minPrice, maxPrice float64
-        var minBedrooms int
-        var err error
+	minFeatures, maxFeatures, code := parseFeatureBounds(q)
+	if code != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+		return
+	}
 
-        if minPriceStr != "" {
-            minPrice, err = strconv.ParseFloat(minPriceStr, 64)
-            if err != nil || minPrice < 0 {
-                writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_min_price"})
-                return
-            }
-        }
+	// amenity=a&amenity=b or amenities=a,b; amenities_match=all (default) | any
+	var amenities []string
+	for _, a := range append(q["amenity"], strings.Split(q.Get("amenities"), ",")...) {
+		if a = strings.TrimSpace(a); a != "" {
+			amenities = append(amenities, a)
+		}
+	}
+	switch q.Get("amenities_match") {
+	case "", "all":
+		filter.Amenities = amenities
+	case "any":
+		filter.AnyAmenities = amenities
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_amenities_match"})
+		return
+	}
 
-        if maxPriceStr != "" {
-            maxPrice, err = strconv.ParseFloat(maxPriceStr, 64)
-            if err != nil || maxPrice < 0 {
-                writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_max_price"})
-                return
-            }
-        }
+	var text storage.TextQuery
+	if v := q.Get("q"); v != "" {
+		if text = storage.ParseTextQuery(v); text == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_q"})
+			return
+		}
+	}
 
-        if minPriceStr != "" && maxPriceStr != "" && minPrice > maxPrice {
-            writeJSON(w, http.StatusBadRequest, map[string]string{"error": "min_price_gt_max_price"})
-            return
-        }
+	facets, ok := s.parseFacets(q)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_facets"})
+		return
+	}
+	if v := q.Get("price_buckets"); v != "" {
+		edges, err := ParsePriceBuckets(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_price_buckets"})
+			return
+		}
+		facets.PriceEdges = edges
+	}
 
-        if minBedroomsStr != "" {
-            minBedrooms, err = strconv.Atoi(minBedroomsStr)
-            if err != nil || minBedrooms < 0 {
-                writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_min_bedrooms"})
-                return
-            }
-        }
+	filter.MinFeatures, filter.MaxFeatures, filter.Text = minFeatures, maxFeatures, text
+	if strings.TrimSpace(location) != "" {
+		filter.Locations = []string{location}
+	}
+	if code := parseGeo(q, &filter); code != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+		return
+	}
+	if filter.Near == nil && slices.ContainsFunc(sortKeys, func(k storage.SortKey) bool { return k.Field == "distance" }) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "distance_sort_without_near"})
+		return
+	}
+	params := ListParams{
+		Filter: filter,
+		Page:   storage.Page{Sort: sortKeys, Limit: limit, Offset: offset},
+	}
+	// keyset mode: cursor replaces offset
+	if v := q.Get("cursor"); v != "" {
+		if q.Get("offset") != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cursor_with_offset"})
+			return
+		}
+		c, err := storage.DecodeCursor(v)
+		if err != nil || !c.Valid(sortKeys, filter) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_cursor"})
+			return
+		}
+		params.Page.Cursor = c
+	}
 
-        params := ListParams{
-            Limit:       limit,
-            Offset:      offset,
-            Location:    location,
-            MinPrice:    minPriceStr,
-            MaxPrice:    maxPriceStr,
-            MinBedrooms: minBedroomsStr,
-            Sort:        sortBy,
-        
-        }
+	// view=summary (default) | full; fields= picks fields from the full object
+	view := q.Get("view")
+	if view != "" && view != "summary" && view != "full" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_view"})
+		return
+	}
+	var fields *fieldSet
+	if v, ok := q["fields"]; ok {
+		if fields, err = parseFields(strings.Join(v, ",")); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_fields", "detail": err.Error()})
+			return
+		}
+	}
 
-        repo := s.PropsRepo
-        if repo == nil {
-            repo = &InMemoryPropertiesRepo{S: s}
-        }
+	props, total, next, err := s.repo().List(r.Context(), params)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
 
-        items, total := repo.List(r.Context(), params)
+	resp := PropertiesListResponse{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	}
+	if next != nil {
+		resp.NextCursor = next.Encode()
+	}
+	if facets.Any() {
+		f, err := s.repo().Facets(r.Context(), params.Filter, facets)
+		if err != nil {
+			writeRepoError(w, err)
+			return
+		}
+		resp.Facets = &f
+	}
 
-        writeJSON(w, http.StatusOK, PropertiesListResponse{
-            Limit:  limit,
-            Offset: offset,
-            Total:  total,
-            Items:  items,
-        })
+	// other views replace items; the outer field shadows the embedded one
+	var items any
+	switch {
+	case fields != nil:
+		projected := make([]map[string]any, len(props))
+		for i, p := range props {
+			projected[i] = fields.project(p, filter)
+		}
+		items = projected
+	case view == "full":
+		if props == nil {
+			props = []domain.Property{}
+		}
+		items = props
+	default:
+		resp.Items = make([]PropertySummary, len(props))
+		for i, p := range props {
+			resp.Items[i] = listSummary(p, filter)
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		PropertiesListResponse
+		Items any `json:"items"`
+	}{resp, items})
 
 }
 
@@ -213,25 +481,38 @@ func (s *Server) handlePropertiesGetByID(w http.ResponseWriter, r *http.Request)
 
 	switch r.Method {
 	case http.MethodGet:
-		for _, p := range s.Properties {
-			if p.ID == id {
-				writeJSON(w, http.StatusOK, p)
-				return
-			}
+		p, ok, err := s.repo().Get(r.Context(), id)
+		if err != nil {
+			writeRepoError(w, err)
+			return
 		}
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		w.Header().Set("ETag", etag(p.Version))
+		writeJSON(w, http.StatusOK, p)
 		return
 
 	case http.MethodDelete:
-		for i, p := range s.Properties {
-			if p.ID == id {
-				// remove element by index
-				s.Properties = append(s.Properties[:i], s.Properties[i+1:]...)
-				writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
-				return
-			}
+		ok, err := s.repo().Delete(r.Context(), id)
+		if err != nil {
+			writeRepoError(w, err)
+			return
 		}
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+		return
+
+	case http.MethodPut:
+		s.handlePropertiesReplace(w, r, id)
+		return
+
+	case http.MethodPatch:
+		s.handlePropertiesPatch(w, r, id)
 		return
 
 	default:
@@ -241,6 +522,9 @@ func (s *Server) handlePropertiesGetByID(w http.ResponseWriter, r *http.Request)
 }
 
 type CreatePropertyRequest struct {
+	// ID is an optional external id chosen by the client (create only);
+	// it must be unique. Without it the server issues one (storage.NewPropertyID).
+	ID          string          `json:"id,omitempty"`
 	Title       string          `json:"title"`
 	Location    string          `json:"location"`
 	Price       float64         `json:"price"`
@@ -251,6 +535,8 @@ type CreatePropertyRequest struct {
 	ImageURLs   []string        `json:"image_urls"`
 	Amenities   []string        `json:"amenities"`
 	Features    domain.Features `json:"features"`
+
+	Coordinates *domain.GeoPoint `json:"coordinates,omitempty"`
 }
 
 func (s *Server) handlePropertiesCreate(w http.ResponseWriter, r *http.Request) {
@@ -265,19 +551,61 @@ func (s *Server) handlePropertiesCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// minimal validation
-	if req.Title == "" || req.Location == "" {
-		http.Error(w, "title and location are required", http.StatusBadRequest)
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if req.Price <= 0 {
-		http.Error(w, "price must be > 0", http.StatusBadRequest)
+
+	if req.ID != "" && !validPropertyID(req.ID) {
+		http.Error(w, "id must be 1-64 characters of A-Z a-z 0-9 . _ -", http.StatusBadRequest)
+		return
+	}
+
+	p := req.property(req.ID)
+	p, err := s.repo().Create(r.Context(), p)
+	if errors.Is(err, storage.ErrDuplicateID) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "id_exists"})
+		return
+	}
+	if err != nil {
+		writeRepoError(w, err)
 		return
 	}
+	w.Header().Set("ETag", etag(p.Version))
+	writeJSON(w, http.StatusCreated, p)
+}
 
-	id := "p-" + strconv.FormatInt(int64(len(s.Properties)+1), 10)
+// validate is the minimal validation shared by create and update.
+func (req CreatePropertyRequest) validate() error {
+	if req.Title == "" || req.Location == "" {
+		return errors.New("title and location are required")
+	}
+	if req.Price <= 0 {
+		return errors.New("price must be > 0")
+	}
+	if req.Coordinates != nil && !storage.ValidGeoPoint(*req.Coordinates) {
+		return errors.New("coordinates must have lat in [-90, 90] and lon in [-180, 180]")
+	}
+	return nil
+}
 
-	p := domain.Property{
+// validPropertyID keeps client ids safe to use as a /properties/{id} path segment.
+func validPropertyID(id string) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return id != "." && id != ".."
+}
+
+func (req CreatePropertyRequest) property(id string) domain.Property {
+	return domain.Property{
 		ID:          id,
 		Title:       req.Title,
 		Location:    req.Location,
@@ -289,10 +617,155 @@ func (s *Server) handlePropertiesCreate(w http.ResponseWriter, r *http.Request)
 		ImageURLs:   req.ImageURLs,
 		Amenities:   req.Amenities,
 		Features:    req.Features,
+		Coordinates: req.Coordinates,
 	}
+}
 
-	s.Properties = append(s.Properties, p)
-	writeJSON(w, http.StatusCreated, p)
+// handlePropertiesReplace is PUT /properties/{id}: the body replaces the
+// whole property (same shape and validation as create), the id is kept.
+func (s *Server) handlePropertiesReplace(w http.ResponseWriter, r *http.Request, id string) {
+	var req CreatePropertyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	s.editProperty(w, r, id, func(domain.Property) (CreatePropertyRequest, error) { return req, nil })
+}
+
+// handlePropertiesPatch is PATCH /properties/{id} with JSON merge-patch
+// (RFC 7396) semantics: objects merge recursively, null removes a field,
+// anything else replaces it. The result is validated like create.
+func (s *Server) handlePropertiesPatch(w http.ResponseWriter, r *http.Request, id string) {
+	var patch any
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if _, ok := patch.(map[string]any); !ok {
+		http.Error(w, "merge patch must be a JSON object", http.StatusBadRequest)
+		return
+	}
+
+	s.editProperty(w, r, id, func(cur domain.Property) (CreatePropertyRequest, error) {
+		var doc any
+		b, _ := json.Marshal(cur)
+		_ = json.Unmarshal(b, &doc)
+		b, _ = json.Marshal(mergePatch(doc, patch))
+
+		var req CreatePropertyRequest
+		if err := json.Unmarshal(b, &req); err != nil {
+			return req, fmt.Errorf("invalid patch: %v", err)
+		}
+		return req, nil
+	})
+}
+
+// maxEditRetries bounds how often an edit without If-Match is redone after
+// losing a race with a concurrent update.
+const maxEditRetries = 3
+
+// editProperty is the read-modify-write shared by PUT and PATCH. The update is
+// conditional on the version that was read, so concurrent edits never
+// overwrite each other: with If-Match the client gets 412, without it the
+// edit is applied again on top of the newer version, and only after
+// maxEditRetries lost races the client gets 409 and may simply retry.
+func (s *Server) editProperty(w http.ResponseWriter, r *http.Request, id string, build func(cur domain.Property) (CreatePropertyRequest, error)) {
+	ifMatchHeader := r.Header.Get("If-Match")
+	for attempt := 0; ; attempt++ {
+		cur, ok, err := s.repo().Get(r.Context(), id)
+		if err != nil {
+			writeRepoError(w, err)
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		if ifMatchHeader != "" && !ifMatch(ifMatchHeader, cur.Version) {
+			writePreconditionFailed(w, cur.Version)
+			return
+		}
+
+		req, err := build(cur)
+		if err == nil {
+			err = req.validate()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		p, ok, err := s.repo().Update(r.Context(), req.property(id), cur.Version)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			if ifMatchHeader != "" {
+				writePreconditionFailed(w, 0)
+				return
+			}
+			if attempt < maxEditRetries {
+				continue
+			}
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "edit_conflict"})
+			return
+		}
+		if err != nil {
+			writeRepoError(w, err)
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		w.Header().Set("ETag", etag(p.Version))
+		writeJSON(w, http.StatusOK, p)
+		return
+	}
+}
+
+// etag is the strong entity tag of a property version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch reports whether an If-Match header ("*" or a list of entity tags)
+// matches the version. Weak tags never match, as If-Match compares strongly.
+func ifMatch(header string, version int64) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// writePreconditionFailed answers 412 with the current ETag when it is known.
+func writePreconditionFailed(w http.ResponseWriter, version int64) {
+	if version > 0 {
+		w.Header().Set("ETag", etag(version))
+	}
+	writeJSON(w, http.StatusPreconditionFailed, map[string]string{"error": "precondition_failed"})
+}
+
+// mergePatch applies an RFC 7396 merge patch to target.
+func mergePatch(target, patch any) any {
+	pm, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	tm, ok := target.(map[string]any)
+	if !ok {
+		tm = make(map[string]any, len(pm))
+	}
+	for k, v := range pm {
+		if v == nil {
+			delete(tm, k)
+			continue
+		}
+		tm[k] = mergePatch(tm[k], v)
+	}
+	return tm
 }
 
 func parseLimitOffset(r *http.Request, defLimit, defOffset int) (int, int) {
@@ -426,6 +899,7 @@ func (s *Server) handleDemo(w http.ResponseWriter, r *http.Request) {
         <div><b>Результат подбора</b></div>
         <div id="summary" class="muted" style="margin-top:8px;">Нажми Match…</div>
         <div id="results" class="list" style="margin-top:10px;"></div>
+        <div id="suggestions" class="list" style="margin-top:10px;"></div>
         <pre id="out" style="display:none;"></pre>
       </div>
     </div>
@@ -461,6 +935,7 @@ const ta = document.getElementById("payload");
 const out = document.getElementById("out");
 const summaryEl = document.getElementById("summary");
 const resultsEl = document.getElementById("results");
+const suggestionsEl = document.getElementById("suggestions");
 const listEl = document.getElementById("list");
 const detailsEl = document.getElementById("details");
 const imagesEl = document.getElementById("images");
@@ -602,10 +1077,34 @@ function buildPayloadFromForm() {
   return payload;
 }
 
-document.getElementById("btnMatch").addEventListener("click", async () => {
+function renderSuggestions(payload, suggestions) {
+  suggestionsEl.innerHTML = "";
+  if (!Array.isArray(suggestions) || suggestions.length === 0) return;
+
+  const head = document.createElement("div");
+  head.className = "muted";
+  head.innerHTML = "<b>Можно ослабить условия:</b>";
+  suggestionsEl.appendChild(head);
+
+  for (const sg of suggestions) {
+    const div = document.createElement("div");
+    div.className = "item";
+    // текст из ответа только через textContent: message содержит ввод пользователя
+    const msg = document.createElement("div");
+    msg.textContent = sg.message;
+    const meta = document.createElement("div");
+    meta.className = "muted";
+    meta.textContent = "+" + sg.unlocked + " объектов" + (sg.fills_limit ? " • заполнит список" : "");
+    div.append(msg, meta);
+    // по клику повторяем подбор с ослабленным профилем
+    div.addEventListener("click", () => runMatch({ ...payload, profile: sg.profile }));
+    suggestionsEl.appendChild(div);
+  }
+}
+
+async function runMatch(payload) {
   out.textContent = "Запрос...";
   try {
-    const payload = buildPayloadFromForm();
     ta.value = JSON.stringify(payload, null, 2);
 
     const res = await fetch("/match", {
@@ -624,6 +1123,7 @@ document.getElementById("btnMatch").addEventListener("click", async () => {
     }
 
     const results = (data && data.results) ? data.results : [];
+    renderSuggestions(payload, data && data.suggestions);
     if (results.length === 0) {
       summaryEl.textContent = "Ничего не найдено по условиям.";
       resultsEl.innerHTML = "";
@@ -654,8 +1154,17 @@ document.getElementById("btnMatch").addEventListener("click", async () => {
         "</div>" +
         "<div class='muted'>ID: <code>" + (p.id || "") + "</code> • " + (p.location || "") + " • " + money(p.price) + "</div>" +
         (p.description ? "<div style='margin-top:6px;'>" + p.description + "</div>" : "") +
-        "<div class='muted' style='margin-top:8px;'><b>Почему подходит:</b></div>" +
-        "<div class='muted'>" + reasons.map(x => "• " + x.message).join("<br/>") + "</div>";
+        "<div class='muted' style='margin-top:8px;'><b>Почему подходит:</b></div>";
+
+      // причины только через textContent: message содержит ввод пользователя
+      const reasonsEl = document.createElement("div");
+      reasonsEl.className = "muted";
+      for (const x of reasons) {
+        const line = document.createElement("div");
+        line.textContent = "• " + x.message;
+        reasonsEl.appendChild(line);
+      }
+      div.appendChild(reasonsEl);
 
       // по клику сразу открываем детали справа
       div.addEventListener("click", async () => {
@@ -671,7 +1180,9 @@ document.getElementById("btnMatch").addEventListener("click", async () => {
   } catch (e) {
     out.textContent = "Ошибка: " + e.message;
   }
-});
+}
+
+document.getElementById("btnMatch").addEventListener("click", () => runMatch(buildPayloadFromForm()));
 
 // Auto-load list on open
 loadProperties();
@@ -683,79 +1194,254 @@ loadProperties();
 	_, _ = w.Write([]byte(html))
 }
 
+// ListParams is a validated GET /properties query. Items come by Page.Sort,
+// by relevance for a Filter.Text without it, and by id last; with Filter.Text
+// they carry a snippet.
 type ListParams struct {
-    Limit       int
-    Offset      int
-    Location    string
-    MinPrice    string
-    MaxPrice    string
-    MinBedrooms string
-    Sort        string
+	Filter storage.PropertyFilter
+	Page   storage.Page
 }
 
-type PropertiesRepo interface {
-    List(ctx context.Context, p ListParams) ([]PropertySummary, int)
+// parseRange reads the optional non-negative min_<name> and max_<name>. On a
+// bad value it returns the error code to answer with.
+func parseRange[T int | float64](q url.Values, name string, parse func(string) (T, error), min, max *T) string {
+	for _, bound := range []struct {
+		param string
+		v     *T
+	}{{"min_" + name, min}, {"max_" + name, max}} {
+		s := q.Get(bound.param)
+		if s == "" {
+			continue
+		}
+		v, err := parse(s)
+		if err != nil || v < 0 {
+			return "invalid_" + bound.param
+		}
+		*bound.v = v
+	}
+	if *min > 0 && *max > 0 && *min > *max {
+		return "min_" + name + "_gt_max_" + name
+	}
+	return ""
 }
 
-type InMemoryPropertiesRepo struct {
-    S *Server
+// parseFloatParam is strconv.ParseFloat that also rejects NaN and infinities.
+func parseFloatParam(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err == nil && (math.IsNaN(v) || math.IsInf(v, 0)) {
+		err = fmt.Errorf("not a finite number: %q", s)
+	}
+	return v, err
 }
 
-func (r *InMemoryPropertiesRepo) List(ctx context.Context, p ListParams) ([]PropertySummary, int) {
-    location := strings.ToLower(p.Location)
-    minPrice, _ := strconv.ParseFloat(p.MinPrice, 64)
-    maxPrice, _ := strconv.ParseFloat(p.MaxPrice, 64)
-    minBedrooms, _ := strconv.Atoi(p.MinBedrooms)
+// parseGeo reads near=lat,lon with radius_km= and bbox=min_lat,min_lon,max_lat,max_lon
+// into f; near alone only gives the distance sort its origin. A bbox with
+// min_lon > max_lon crosses the antimeridian.
+func parseGeo(q url.Values, f *storage.PropertyFilter) string {
+	if v := q.Get("near"); v != "" {
+		c, ok := parseCoords(v, 2)
+		if !ok {
+			return "invalid_near"
+		}
+		f.Near = &domain.GeoPoint{Lat: c[0], Lon: c[1]}
+		if !storage.ValidGeoPoint(*f.Near) {
+			return "invalid_near"
+		}
+	}
+	if v := q.Get("radius_km"); v != "" {
+		r, err := parseFloatParam(v)
+		if err != nil || r <= 0 {
+			return "invalid_radius_km"
+		}
+		if f.Near == nil {
+			return "radius_km_without_near"
+		}
+		f.RadiusKm = r
+	}
+	if v := q.Get("bbox"); v != "" {
+		c, ok := parseCoords(v, 4)
+		if !ok {
+			return "invalid_bbox"
+		}
+		b := storage.BBox{MinLat: c[0], MinLon: c[1], MaxLat: c[2], MaxLon: c[3]}
+		if !storage.ValidGeoPoint(domain.GeoPoint{Lat: b.MinLat, Lon: b.MinLon}) ||
+			!storage.ValidGeoPoint(domain.GeoPoint{Lat: b.MaxLat, Lon: b.MaxLon}) || b.MinLat > b.MaxLat {
+			return "invalid_bbox"
+		}
+		f.BBox = &b
+	}
+	return ""
+}
 
-    filtered := make([]domain.Property, 0, len(r.S.Properties))
-    for _, prop := range r.S.Properties {
-        if location != "" && !strings.Contains(strings.ToLower(prop.Location), location) {
-            continue
-        }
-        if minPrice > 0 && prop.Price < minPrice {
-            continue
-        }
-        if maxPrice > 0 && prop.Price > maxPrice {
-            continue
-        }
-        if minBedrooms > 0 && prop.Bedrooms < minBedrooms {
-            continue
-        }
-        filtered = append(filtered, prop)
-    }
+// parseCoords parses exactly n comma-separated finite numbers.
+func parseCoords(v string, n int) ([]float64, bool) {
+	parts := strings.Split(v, ",")
+	if len(parts) != n {
+		return nil, false
+	}
+	out := make([]float64, n)
+	for i, part := range parts {
+		x, err := parseFloatParam(strings.TrimSpace(part))
+		if err != nil {
+			return nil, false
+		}
+		out[i] = x
+	}
+	return out, true
+}
 
-    switch p.Sort {
-    case "price_asc":
-        sort.Slice(filtered, func(i, j int) bool { return filtered[i].Price < filtered[j].Price })
-    case "price_desc":
-        sort.Slice(filtered, func(i, j int) bool { return filtered[i].Price > filtered[j].Price })
-    }
+// DefaultPriceBuckets are the price facet edges when neither the server nor
+// the request sets them.
+var DefaultPriceBuckets = []float64{100000, 200000, 300000, 500000, 1000000}
 
-    total := len(filtered)
-    offset := p.Offset
-    limit := p.Limit
-    if offset > total {
-        offset = total
-    }
-    end := offset + limit
-    if end > total {
-        end = total
-    }
+// parseFacets reads facets=location,bedrooms,amenities,price; ok is false for
+// an unknown name.
+func (s *Server) parseFacets(q url.Values) (req storage.FacetRequest, ok bool) {
+	req.PriceEdges = s.PriceBuckets
+	if req.PriceEdges == nil {
+		req.PriceEdges = DefaultPriceBuckets
+	}
+	for _, name := range strings.Split(q.Get("facets"), ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "location":
+			req.Location = true
+		case "bedrooms":
+			req.Bedrooms = true
+		case "amenities":
+			req.Amenities = true
+		case "price":
+			req.Price = true
+		default:
+			return req, false
+		}
+	}
+	return req, true
+}
 
-    items := make([]PropertySummary, 0, end-offset)
-    for _, prop := range filtered[offset:end] {
-        items = append(items, PropertySummary{
-            ID:        prop.ID,
-            Title:     prop.Title,
-            Location:  prop.Location,
-            Price:     prop.Price,
-            Bedrooms:  prop.Bedrooms,
-            Bathrooms: prop.Bathrooms,
-            AreaSQM:   prop.AreaSQM,
-            Amenities: prop.Amenities,
-        })
-    }
-    return items, total
+// ParsePriceBuckets parses comma-separated price facet edges; they must be
+// non-negative and strictly ascending.
+func ParsePriceBuckets(v string) ([]float64, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) > 50 {
+		return nil, fmt.Errorf("too many price buckets (max 50)")
+	}
+	edges := make([]float64, 0, len(parts))
+	for _, p := range parts {
+		e, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || e < 0 || math.IsNaN(e) || math.IsInf(e, 0) {
+			return nil, fmt.Errorf("invalid price bucket edge %q", p)
+		}
+		if len(edges) > 0 && e <= edges[len(edges)-1] {
+			return nil, fmt.Errorf("price bucket edges must be ascending")
+		}
+		edges = append(edges, e)
+	}
+	return edges, nil
+}
+
+// featureParamAliases are shorter names for min_/max_<feature> parameters.
+var featureParamAliases = map[string]string{
+	"sea_km": "distance_to_sea_km",
 }
-	
 
+// parseFeatureBounds reads min_<feature> and max_<feature> for every feature
+// key and alias. On a bad value it returns the error code to answer with.
+func parseFeatureBounds(q url.Values) (min, max map[string]float64, code string) {
+	names := domain.FeatureKeys()
+	for alias := range featureParamAliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names) // stable error for several bad values
+
+	for _, name := range names {
+		key := name
+		if k, ok := featureParamAliases[name]; ok {
+			key = k
+		}
+		for _, bound := range []struct {
+			prefix string
+			m      *map[string]float64
+		}{{"min_", &min}, {"max_", &max}} {
+			v := q.Get(bound.prefix + name)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+				return nil, nil, "invalid_" + bound.prefix + name
+			}
+			if *bound.m == nil {
+				*bound.m = make(map[string]float64)
+			}
+			(*bound.m)[key] = n
+		}
+	}
+	for _, key := range domain.FeatureKeys() {
+		lo, okLo := min[key]
+		if hi, ok := max[key]; ok && okLo && lo > hi {
+			return nil, nil, "min_" + key + "_gt_max_" + key
+		}
+	}
+	return min, max, ""
+}
+
+// PropertiesRepo is the property storage behind the /properties handlers.
+// Get, Update and Delete report a missing id as ok=false, not as an error;
+// errors mean the backend failed and are answered with 500.
+type PropertiesRepo interface {
+	// List returns a page of full properties, the total number of matches
+	// and, when more rows follow, the cursor of the next page.
+	List(ctx context.Context, p ListParams) ([]domain.Property, int, *storage.Cursor, error)
+	Get(ctx context.Context, id string) (domain.Property, bool, error)
+	// Create stores p as version 1, assigning an id when p.ID is empty.
+	Create(ctx context.Context, p domain.Property) (domain.Property, error)
+	// Update replaces the property with p.ID and returns it with the version
+	// bumped. With ifVersion > 0 it fails with storage.ErrVersionConflict if
+	// the stored version differs.
+	Update(ctx context.Context, p domain.Property, ifVersion int64) (domain.Property, bool, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	// Facets counts over every property matching f, ignoring paging.
+	Facets(ctx context.Context, f storage.PropertyFilter, req storage.FacetRequest) (storage.Facets, error)
+}
+
+func toSummary(p domain.Property) PropertySummary {
+	return PropertySummary{
+		ID:        p.ID,
+		Title:     p.Title,
+		Location:  p.Location,
+		Price:     p.Price,
+		Bedrooms:  p.Bedrooms,
+		Bathrooms: p.Bathrooms,
+		AreaSQM:   p.AreaSQM,
+		Amenities: p.Amenities,
+
+		Coordinates: p.Coordinates,
+	}
+}
+
+// listSummary is toSummary with the search snippet when f has text and the
+// distance when it has a near point.
+func listSummary(p domain.Property, f storage.PropertyFilter) PropertySummary {
+	sum := toSummary(p)
+	if f.Text != nil {
+		sum.Snippet = f.Text.Snippet(p)
+	}
+	sum.DistanceKm = distanceKm(p, f)
+	return sum
+}
+
+// distanceKm is the distance from f.Near, nil when it is unknown.
+func distanceKm(p domain.Property, f storage.PropertyFilter) *float64 {
+	if f.Near == nil || p.Coordinates == nil {
+		return nil
+	}
+	d := storage.DistanceKm(*f.Near, *p.Coordinates)
+	return &d
+}
+
+// writeRepoError answers a storage failure with 500; details go to the log only.
+func writeRepoError(w http.ResponseWriter, err error) {
+	log.Printf("properties repo: %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal"})
+}
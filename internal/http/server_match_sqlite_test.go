@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/matching"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
+)
+
+func TestPOSTMatch_SQLiteFullCatalog(t *testing.T) {
+	t.Parallel()
+
+	store, err := storage.OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer store.Close()
+	if err := store.EnsureSchema(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	// 50 объектов; лучшие — в конце (id p-040..p-049), за пределами первых 20 строк
+	var seed []domain.Property
+	for i := 0; i < 50; i++ {
+		seed = append(seed, domain.Property{
+			ID: fmt.Sprintf("p-%03d", i), Title: "T", Location: "Valencia",
+			Price: 200000 + float64(i)*10000, Bedrooms: 2,
+			Features: domain.Features{Quietness: float64(i) / 50},
+		})
+	}
+	if err := store.UpsertMany(seed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	srv := NewServer(matching.NewEngine(matching.DefaultWeights()), nil)
+	srv.PropsRepo = &SQLitePropertiesRepo{Store: store}
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	match := func(body string) MatchResponse {
+		resp, err := http.Post(ts.URL+"/match", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("POST /match: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status=%d", resp.StatusCode)
+		}
+		var got MatchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return got
+	}
+
+	body := `{"profile": {"budget_max": 680000, "priorities": {"quietness": 1}}, "limit": 3}`
+	got := match(body)
+	if len(got.Results) != 3 || got.Results[0].Property.ID != "p-048" {
+		t.Fatalf("results=%+v want p-048 first (p-049 is over budget)", got.Results)
+	}
+
+	// объект, созданный после старта, тоже участвует в подборе
+	if _, err := store.CreateProperty(domain.Property{ID: "new", Title: "N", Location: "Valencia", Price: 300000,
+		Features: domain.Features{Quietness: 1}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got := match(body); got.Results[0].Property.ID != "new" {
+		t.Fatalf("first=%q want new", got.Results[0].Property.ID)
+	}
+
+	// rejections are computed on the whole table, not the SQL-prefiltered rows
+	got = match(`{"profile": {"budget_max": 250000}, "limit": 10, "explain_rejections": true}`)
+	if len(got.Results) != 6 || got.RejectionCounts["budget_max"] != 45 {
+		t.Fatalf("results=%d rejection_counts=%v", len(got.Results), got.RejectionCounts)
+	}
+	if len(got.Suggestions) == 0 || got.Suggestions[0].Constraint != "budget_max" {
+		t.Fatalf("suggestions=%+v", got.Suggestions)
+	}
+}
@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+	"github.com/denisok6893-rgb/ai-property-matching/internal/matching"
+)
+
+func TestPOSTMatch_WeightsPresetAndOverride(t *testing.T) {
+	t.Parallel()
+
+	base := matching.DefaultWeights()
+	engine := matching.NewEngine(base)
+	engine.SetPresets(map[string][]byte{"investor": []byte(`{"investment_focus": 2}`)})
+
+	srv := NewServer(engine, []domain.Property{
+		{ID: "es-001", Title: "A", Location: "Valencia", Price: 300000, Features: domain.Features{Quietness: 0.5, InvestmentPotential: 0.9}},
+	})
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	post := func(body string) (*http.Response, MatchResponse) {
+		resp, err := http.Post(ts.URL+"/match", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("POST /match: %v", err)
+		}
+		defer resp.Body.Close()
+		var got MatchResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return resp, got
+	}
+
+	profile := `"profile": {"priorities": {"quietness": 1, "investment_focus": 1}}`
+
+	// global weights echoed by default
+	_, got := post(`{` + profile + `}`)
+	if got.Weights.InvestmentFocus != base.InvestmentFocus || got.WeightsPreset != "" {
+		t.Fatalf("default weights=%+v preset=%q", got.Weights, got.WeightsPreset)
+	}
+	defaultScore := got.Results[0].Score
+
+	// preset + partial override on top of it
+	_, got = post(`{` + profile + `, "weights_preset": "investor", "weights_override": {"quietness": 0.1}}`)
+	if got.WeightsPreset != "investor" || got.Weights.InvestmentFocus != 2 || got.Weights.Quietness != 0.1 {
+		t.Fatalf("effective weights=%+v preset=%q", got.Weights, got.WeightsPreset)
+	}
+	if got.Weights.SunExposure != base.SunExposure {
+		t.Fatalf("override must keep unspecified fields, sun_exposure=%v", got.Weights.SunExposure)
+	}
+	if got.Results[0].Score <= defaultScore {
+		t.Fatalf("investor score=%v want > %v", got.Results[0].Score, defaultScore)
+	}
+
+	// the server's global engine is untouched
+	if srv.Engine.Weights().Quietness != base.Quietness {
+		t.Fatalf("global weights mutated")
+	}
+
+	if resp, _ := post(`{` + profile + `, "weights_preset": "nope"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown preset status=%d", resp.StatusCode)
+	}
+	if resp, _ := post(`{` + profile + `, "weights_override": {"curves": {"sea_proximity": {"type": "bad"}}}}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid override status=%d", resp.StatusCode)
+	}
+}
+
+func TestPOSTMatchExplain(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(matching.NewEngine(matching.DefaultWeights()), []domain.Property{
+		{ID: "es-001", Title: "A", Location: "Valencia", Price: 300000, Features: domain.Features{Quietness: 0.6}},
+	})
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	post := func(body string) *http.Response {
+		resp, err := http.Post(ts.URL+"/match/explain", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("POST /match/explain: %v", err)
+		}
+		return resp
+	}
+
+	resp := post(`{"profile": {"budget_max": 400000, "priorities": {"quietness": 1}}, "property_id": "es-001"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d", resp.StatusCode)
+	}
+	var got MatchExplainResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.PropertyID != "es-001" || got.WeightsVersion != 1 || len(got.Factors) == 0 {
+		t.Fatalf("got=%+v", got)
+	}
+
+	missing := post(`{"profile": {}, "property_id": "nope"}`)
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown id status=%d", missing.StatusCode)
+	}
+}
+
+func TestPOSTMatch_Lang(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(matching.NewEngine(matching.DefaultWeights()), []domain.Property{
+		{ID: "es-001", Title: "A", Location: "Valencia", Price: 300000, Features: domain.Features{DistanceToSeaKm: 1.5}},
+	})
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	post := func(body, acceptLang string) (int, MatchResponse) {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/match", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if acceptLang != "" {
+			req.Header.Set("Accept-Language", acceptLang)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /match: %v", err)
+		}
+		defer resp.Body.Close()
+		var got MatchResponse
+		if resp.StatusCode == http.StatusOK {
+			_ = json.NewDecoder(resp.Body).Decode(&got)
+		}
+		return resp.StatusCode, got
+	}
+
+	profile := `"profile": {"priorities": {"sea_proximity": 1}}`
+	if _, got := post(`{`+profile+`}`, "ru-RU,ru;q=0.9"); got.Results[0].Reasons[0].Message != "1.5 км до моря" {
+		t.Fatalf("Accept-Language ru: %+v", got.Results[0].Reasons)
+	}
+	if _, got := post(`{`+profile+`, "lang": "es"}`, "ru"); got.Results[0].Reasons[0].Message != "a 1.5 km del mar" {
+		t.Fatalf("lang es: %+v", got.Results[0].Reasons)
+	}
+	if code, _ := post(`{`+profile+`, "lang": "de"}`, ""); code != http.StatusBadRequest {
+		t.Fatalf("unsupported lang status=%d", code)
+	}
+}
+
+func TestPOSTMatch_UnknownFeature(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(matching.NewEngine(matching.DefaultWeights()), []domain.Property{
+		{ID: "es-001", Title: "A", Location: "Valencia", Price: 300000, Features: domain.Features{Quietness: 0.6}},
+	})
+	ts := httptest.NewServer(srv.Routes())
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/match", `{"profile": {"hard_filters": {"min_features": {"quietnes": 0.5}}}}`, http.StatusBadRequest},
+		{"/match/explain", `{"profile": {"hard_filters": {"min_features": {"quietnes": 0.5}}}, "property_id": "es-001"}`, http.StatusBadRequest},
+		// keys are matched like the engine does: trimmed, case-insensitive
+		{"/match", `{"profile": {"hard_filters": {"min_features": {" Quietness": 0.5}}}}`, http.StatusOK},
+	} {
+		resp, err := http.Post(ts.URL+tc.path, "application/json", bytes.NewBufferString(tc.body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", tc.path, err)
+		}
+		var got map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Fatalf("%s %s: status=%d want %d", tc.path, tc.body, resp.StatusCode, tc.want)
+		}
+		if tc.want == http.StatusBadRequest && (got["error"] != "unknown_feature" || got["detail"] != "quietnes") {
+			t.Fatalf("%s: body=%v", tc.path, got)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package httpapi
 
 import (
 	"context"
-	"strconv"
 
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
 	"github.com/denisok6893-rgb/ai-property-matching/internal/storage"
 )
 
@@ -11,42 +11,39 @@ type SQLitePropertiesRepo struct {
 	Store *storage.SQLiteStore
 }
 
-func (r *SQLitePropertiesRepo) List(ctx context.Context, p ListParams) ([]PropertySummary, int) {
-	if r == nil || r.Store == nil {
-		return nil, 0
+func (r *SQLitePropertiesRepo) List(ctx context.Context, p ListParams) ([]domain.Property, int, *storage.Cursor, error) {
+	if p.Filter.Text != nil && len(p.Page.Sort) == 0 {
+		return r.Store.SearchProperties(ctx, p.Filter, p.Page)
 	}
+	return r.Store.ListPropertiesFiltered(p.Filter, p.Page)
+}
 
-	minPrice, _ := strconv.ParseFloat(p.MinPrice, 64)
-	maxPrice, _ := strconv.ParseFloat(p.MaxPrice, 64)
-	minBedrooms, _ := strconv.Atoi(p.MinBedrooms)
-
-	props, total, err := r.Store.ListPropertiesFiltered(
-		p.Limit,
-		p.Offset,
-		p.Location,
-		minPrice,
-		maxPrice,
-		minBedrooms,
-		p.Sort,
-	)
-	if err != nil {
-		// Контракт сейчас не возвращает 500 на ошибки репозитория (у нас нет ошибок в сигнатуре).
-		// Чтобы не менять API/handler в этом шаге — просто "пусто".
-		return nil, 0
-	}
+func (r *SQLitePropertiesRepo) Get(_ context.Context, id string) (domain.Property, bool, error) {
+	return r.Store.GetProperty(id)
+}
+
+func (r *SQLitePropertiesRepo) Create(_ context.Context, p domain.Property) (domain.Property, error) {
+	return r.Store.CreateProperty(p)
+}
+
+func (r *SQLitePropertiesRepo) Update(_ context.Context, p domain.Property, ifVersion int64) (domain.Property, bool, error) {
+	return r.Store.UpdateProperty(p, ifVersion)
+}
+
+func (r *SQLitePropertiesRepo) Delete(_ context.Context, id string) (bool, error) {
+	return r.Store.DeleteProperty(id)
+}
+
+func (r *SQLitePropertiesRepo) Facets(ctx context.Context, f storage.PropertyFilter, req storage.FacetRequest) (storage.Facets, error) {
+	return r.Store.Facets(ctx, f, req)
+}
 
-	out := make([]PropertySummary, 0, len(props))
-	for _, prop := range props {
-		out = append(out, PropertySummary{
-			ID:        prop.ID,
-			Title:     prop.Title,
-			Location:  prop.Location,
-			Price:     prop.Price,
-			Bedrooms:  prop.Bedrooms,
-			Bathrooms: prop.Bathrooms,
-			AreaSQM:   prop.AreaSQM,
-			Amenities: prop.Amenities,
-		})
+// Scan streams the catalog for /match, pushing the profile's hard filters
+// down to SQL.
+func (r *SQLitePropertiesRepo) Scan(ctx context.Context, profile *domain.ClientProfile, fn func(domain.Property) bool) error {
+	var f storage.PropertyFilter
+	if profile != nil {
+		f = hardFilter(*profile)
 	}
-	return out, total
+	return r.Store.ScanProperties(ctx, f, fn)
 }
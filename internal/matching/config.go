@@ -2,8 +2,13 @@ package matching
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 )
 
 // Weights defines coefficients for each preference factor.
@@ -18,6 +23,47 @@ type Weights struct {
 	Walkability        float64 `json:"walkability"`
 	GreenAreas         float64 `json:"green_areas"`
 	SeaProximity       float64 `json:"sea_proximity"`
+
+	// RoomFit weights how closely bedrooms/bathrooms match the desired counts.
+	RoomFit       float64       `json:"room_fit"`
+	RoomTolerance RoomTolerance `json:"room_tolerance"`
+
+	// Curves optionally replace the default linear transform per factor key.
+	Curves map[string]Curve `json:"curves,omitempty"`
+}
+
+// RoomTolerance is the penalty curve for a room count mismatch.
+// Short[i] / Extra[i] is the penalty (0..1) for being i+1 rooms short / over;
+// mismatches beyond the end of a list use its last value. Validate rejects
+// an empty list; weights built in code without one use the default curve, so
+// a one-room miss never scores 0 by omission.
+type RoomTolerance struct {
+	Short []float64 `json:"short"`
+	Extra []float64 `json:"extra"`
+}
+
+// Validate checks that both lists are non-empty and hold penalties in 0..1.
+func (t RoomTolerance) Validate() error {
+	for _, l := range []struct {
+		name   string
+		values []float64
+	}{{"short", t.Short}, {"extra", t.Extra}} {
+		if len(l.values) == 0 {
+			return fmt.Errorf("%s: need at least 1 value", l.name)
+		}
+		for i, v := range l.values {
+			if !in01(v) {
+				return fmt.Errorf("%s: value %d (%v) outside 0..1", l.name, i, v)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultRoomTolerance is the curve DefaultWeights starts from.
+var defaultRoomTolerance = RoomTolerance{
+	Short: []float64{0.5, 0.9, 1.0},
+	Extra: []float64{0.15, 0.35, 0.5},
 }
 
 // DefaultWeights returns a reasonable baseline for MVP.
@@ -33,18 +79,146 @@ func DefaultWeights() Weights {
 		Walkability:        0.7,
 		GreenAreas:         0.6,
 		SeaProximity:       0.8,
+		RoomFit:            0.3,
+		RoomTolerance: RoomTolerance{
+			Short: slices.Clone(defaultRoomTolerance.Short),
+			Extra: slices.Clone(defaultRoomTolerance.Extra),
+		},
 	}
 }
 
-// LoadWeightsFromFile loads weights from JSON file, falling back to defaults on file read errors.
+// LoadWeightsFromFile loads weights from JSON file, falling back to defaults on read or validation errors.
 func LoadWeightsFromFile(path string) (Weights, error) {
-	w := DefaultWeights()
+	return LoadWeightsOver(DefaultWeights(), path)
+}
+
+// LoadWeightsOver reads a (possibly partial) weights JSON file on top of base.
+func LoadWeightsOver(base Weights, path string) (Weights, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
-		return w, fmt.Errorf("read weights file: %w", err)
+		return base, fmt.Errorf("read weights file: %w", err)
+	}
+	return ApplyOverride(base, b)
+}
+
+// ApplyOverride merges a (possibly partial) weights JSON document into a copy
+// of base and validates the result. Fields absent from data keep base values;
+// curves are merged per factor key.
+func ApplyOverride(base Weights, data []byte) (Weights, error) {
+	w := base.clone()
+	if err := json.Unmarshal(data, &w); err != nil {
+		return base, fmt.Errorf("unmarshal weights: %w", err)
 	}
-	if err := json.Unmarshal(b, &w); err != nil {
-		return w, fmt.Errorf("unmarshal weights: %w", err)
+	if err := w.Validate(); err != nil {
+		return base, fmt.Errorf("invalid weights: %w", err)
 	}
 	return w, nil
 }
+
+// ErrPresetDropped marks a preset document that no longer applies over the
+// current weights and was left out; the weights themselves are still in use.
+var ErrPresetDropped = errors.New("preset dropped")
+
+// LoadPresetsFromDir loads every *.json file in dir as a named preset
+// (file name without extension) layered over base.
+func LoadPresetsFromDir(dir string, base Weights) (map[string]Weights, error) {
+	docs, err := LoadPresetDocs(dir, base)
+	if err != nil {
+		return nil, err
+	}
+	return layerPresets(base, docs)
+}
+
+// LoadPresetDocs reads every *.json file in dir as a named preset document
+// (file name without extension), checking that each applies over base. The
+// documents are kept raw so Engine.SetPresets can layer them over whatever
+// weights are current.
+func LoadPresetDocs(dir string, base Weights) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read presets dir: %w", err)
+	}
+	docs := make(map[string][]byte)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), ".json")
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("preset %q: read weights file: %w", name, err)
+		}
+		if _, err := ApplyOverride(base, b); err != nil {
+			return nil, fmt.Errorf("preset %q: %w", name, err)
+		}
+		docs[name] = b
+	}
+	return docs, nil
+}
+
+// layerPresets applies every preset document over base. A document that no
+// longer applies is left out and reported in the error (ErrPresetDropped);
+// both sides were validated on their own, so that takes a curve that is only
+// invalid in combination. The presets that do apply are returned either way.
+func layerPresets(base Weights, docs map[string][]byte) (map[string]Weights, error) {
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	presets := make(map[string]Weights, len(docs))
+	var errs []error
+	for _, name := range names {
+		w, err := ApplyOverride(base, docs[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("preset %q: %w: %w", name, ErrPresetDropped, err))
+			continue
+		}
+		presets[name] = w
+	}
+	return presets, errors.Join(errs...)
+}
+
+// clone deep-copies slices and maps so that unmarshalling into the copy
+// never touches the original.
+func (w Weights) clone() Weights {
+	out := w
+	out.RoomTolerance.Short = append([]float64(nil), w.RoomTolerance.Short...)
+	out.RoomTolerance.Extra = append([]float64(nil), w.RoomTolerance.Extra...)
+	if w.Curves != nil {
+		out.Curves = make(map[string]Curve, len(w.Curves))
+		for k, c := range w.Curves {
+			c.Points = append([][2]float64(nil), c.Points...)
+			out.Curves[k] = c
+		}
+	}
+	return out
+}
+
+// Validate checks the room tolerance and the curve definitions: each curve
+// must target a registered factor that exposes a raw value and have valid
+// parameters for its type.
+func (w Weights) Validate() error {
+	if err := w.RoomTolerance.Validate(); err != nil {
+		return fmt.Errorf("room_tolerance: %w", err)
+	}
+	reg := DefaultRegistry(w)
+	keys := make([]string, 0, len(w.Curves))
+	for k := range w.Curves {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f, ok := reg.Get(key)
+		if !ok {
+			return fmt.Errorf("curve %q: unknown factor", key)
+		}
+		if _, ok := f.(rawValuer); !ok {
+			return fmt.Errorf("curve %q: factor does not support curves", key)
+		}
+		if err := w.Curves[key].Validate(); err != nil {
+			return fmt.Errorf("curve %q: %w", key, err)
+		}
+	}
+	return nil
+}
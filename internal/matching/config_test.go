@@ -0,0 +1,56 @@
+package matching
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadPresetsFromDir(t *testing.T) {
+	t.Parallel()
+
+	base := DefaultWeights()
+	presets, err := LoadPresetsFromDir("../../configs/presets", base)
+	if err != nil {
+		t.Fatalf("load presets: %v", err)
+	}
+	for _, name := range []string{"family", "investor", "retiree"} {
+		if _, ok := presets[name]; !ok {
+			t.Fatalf("preset %q missing in %v", name, presets)
+		}
+	}
+	if presets["investor"].InvestmentFocus != 1.0 {
+		t.Fatalf("investor investment_focus=%v", presets["investor"].InvestmentFocus)
+	}
+	if len(presets["family"].RoomTolerance.Short) == 0 {
+		t.Fatalf("preset must inherit room_tolerance from base")
+	}
+}
+
+func TestApplyOverride_RoomTolerance(t *testing.T) {
+	t.Parallel()
+
+	base := DefaultWeights()
+	cases := []struct {
+		doc    string
+		errHas string
+	}{
+		{`{"room_tolerance": {"short": []}}`, "short: need at least 1 value"},
+		{`{"room_tolerance": {"extra": [0.2, 1.5]}}`, "extra: value 1 (1.5) outside 0..1"},
+		{`{"room_tolerance": {"short": [-0.1]}}`, "outside 0..1"},
+	}
+	for _, c := range cases {
+		w, err := ApplyOverride(base, []byte(c.doc))
+		if err == nil || !strings.Contains(err.Error(), c.errHas) {
+			t.Fatalf("%s: err=%v want containing %q", c.doc, err, c.errHas)
+		}
+		if len(w.RoomTolerance.Short) != len(base.RoomTolerance.Short) {
+			t.Fatalf("%s: rejected override leaked into %+v", c.doc, w.RoomTolerance)
+		}
+	}
+
+	// a partial override keeps the other list from base
+	w, err := ApplyOverride(base, []byte(`{"room_tolerance": {"short": [0.4]}}`))
+	if err != nil || w.RoomTolerance.Short[0] != 0.4 || len(w.RoomTolerance.Extra) != len(base.RoomTolerance.Extra) {
+		t.Fatalf("partial override: %+v err=%v", w.RoomTolerance, err)
+	}
+}
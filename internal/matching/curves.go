@@ -0,0 +1,107 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Curve maps a factor's raw value (e.g. distance_to_sea_km) to a 0..1 score.
+// When a factor has a curve, its output replaces the built-in transform
+// including the lower-is-better inversion, so the curve alone decides what
+// "good" means.
+//
+//	{"type": "linear", "min": 0, "max": 1}                  // (x-min)/(max-min), clamped; min < max
+//	{"type": "threshold", "at": 3, "below": 1, "above": 0}  // x <= at => below, else above
+//	{"type": "sigmoid", "midpoint": 3, "steepness": -2}     // 1/(1+e^(-steepness*(x-midpoint)))
+//	{"type": "piecewise", "points": [[0,1],[3,0.5],[10,0]]} // linear between points, flat outside
+type Curve struct {
+	Type string `json:"type"`
+
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+
+	At    float64 `json:"at,omitempty"`
+	Below float64 `json:"below,omitempty"`
+	Above float64 `json:"above,omitempty"`
+
+	Midpoint  float64 `json:"midpoint,omitempty"`
+	Steepness float64 `json:"steepness,omitempty"`
+
+	Points [][2]float64 `json:"points,omitempty"`
+}
+
+const (
+	CurveLinear    = "linear"
+	CurveThreshold = "threshold"
+	CurveSigmoid   = "sigmoid"
+	CurvePiecewise = "piecewise"
+)
+
+// Validate checks the curve parameters for its type.
+func (c Curve) Validate() error {
+	switch c.Type {
+	case CurveLinear:
+		if c.Min >= c.Max {
+			return fmt.Errorf("linear: min must be less than max (use piecewise for a decreasing curve)")
+		}
+	case CurveThreshold:
+		if !in01(c.Below) || !in01(c.Above) {
+			return fmt.Errorf("threshold: below and above must be within 0..1")
+		}
+		if c.Below == c.Above {
+			return fmt.Errorf("threshold: below and above must differ")
+		}
+	case CurveSigmoid:
+		if c.Steepness == 0 {
+			return fmt.Errorf("sigmoid: steepness must be non-zero")
+		}
+	case CurvePiecewise:
+		if len(c.Points) < 2 {
+			return fmt.Errorf("piecewise: need at least 2 points")
+		}
+		for i, pt := range c.Points {
+			if !in01(pt[1]) {
+				return fmt.Errorf("piecewise: point %d value %v outside 0..1", i, pt[1])
+			}
+			if i > 0 && pt[0] <= c.Points[i-1][0] {
+				return fmt.Errorf("piecewise: point %d x must be greater than previous", i)
+			}
+		}
+	case "":
+		return fmt.Errorf("type is required (linear|threshold|sigmoid|piecewise)")
+	default:
+		return fmt.Errorf("unknown type %q (want linear|threshold|sigmoid|piecewise)", c.Type)
+	}
+	return nil
+}
+
+// Apply maps x to 0..1. The curve is assumed valid.
+func (c Curve) Apply(x float64) float64 {
+	switch c.Type {
+	case CurveLinear:
+		return clamp01((x - c.Min) / (c.Max - c.Min))
+	case CurveThreshold:
+		if x <= c.At {
+			return c.Below
+		}
+		return c.Above
+	case CurveSigmoid:
+		return 1 / (1 + math.Exp(-c.Steepness*(x-c.Midpoint)))
+	case CurvePiecewise:
+		pts := c.Points
+		if x <= pts[0][0] {
+			return pts[0][1]
+		}
+		last := pts[len(pts)-1]
+		if x >= last[0] {
+			return last[1]
+		}
+		i := sort.Search(len(pts), func(i int) bool { return pts[i][0] >= x })
+		a, b := pts[i-1], pts[i]
+		return a[1] + (b[1]-a[1])*(x-a[0])/(b[0]-a[0])
+	}
+	return clamp01(x)
+}
+
+func in01(v float64) bool { return v >= 0 && v <= 1 }
@@ -0,0 +1,104 @@
+package matching
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestCurve_Apply(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		c    Curve
+		x    float64
+		want float64
+	}{
+		{"linear range", Curve{Type: CurveLinear, Min: 2, Max: 6}, 3, 0.25},
+		{"linear clamp", Curve{Type: CurveLinear, Min: 0, Max: 1}, 5, 1},
+		{"threshold below", Curve{Type: CurveThreshold, At: 3, Below: 1, Above: 0}, 3, 1},
+		{"threshold above", Curve{Type: CurveThreshold, At: 3, Below: 1, Above: 0}, 3.1, 0},
+		{"sigmoid midpoint", Curve{Type: CurveSigmoid, Midpoint: 3, Steepness: -2}, 3, 0.5},
+		{"sigmoid far", Curve{Type: CurveSigmoid, Midpoint: 3, Steepness: -2}, 10, 1 / (1 + math.Exp(14))},
+		{"piecewise inside", Curve{Type: CurvePiecewise, Points: [][2]float64{{0, 1}, {2, 0.5}, {10, 0}}}, 1, 0.75},
+		{"piecewise left", Curve{Type: CurvePiecewise, Points: [][2]float64{{1, 1}, {2, 0}}}, 0, 1},
+		{"piecewise right", Curve{Type: CurvePiecewise, Points: [][2]float64{{1, 1}, {2, 0.2}}}, 5, 0.2},
+	}
+	for _, c := range cases {
+		if err := c.c.Validate(); err != nil {
+			t.Fatalf("%s: validate: %v", c.name, err)
+		}
+		if got := c.c.Apply(c.x); math.Abs(got-c.want) > 1e-9 {
+			t.Fatalf("%s: Apply(%v)=%v want=%v", c.name, c.x, got, c.want)
+		}
+	}
+}
+
+func TestWeights_ValidateCurves(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		curves map[string]Curve
+		errHas string
+	}{
+		{map[string]Curve{"sea_proximity": {Type: "cubic"}}, `unknown type "cubic"`},
+		{map[string]Curve{"sea_proximity": {Type: CurveLinear}}, "min must be less than max"},
+		{map[string]Curve{"sea_proximity": {Type: CurveLinear, Min: 10, Max: 0}}, "min must be less than max"},
+		{map[string]Curve{"sea_proximity": {Type: CurveLinear, Min: 3, Max: 3}}, "min must be less than max"},
+		{map[string]Curve{"sea_proximity": {}}, "type is required"},
+		{map[string]Curve{"sea_proximity": {Type: CurveSigmoid, Midpoint: 3}}, "steepness must be non-zero"},
+		{map[string]Curve{"sea_proximity": {Type: CurveThreshold, At: 3, Below: 2}}, "within 0..1"},
+		{map[string]Curve{"sea_proximity": {Type: CurvePiecewise, Points: [][2]float64{{0, 1}}}}, "at least 2 points"},
+		{map[string]Curve{"sea_proximity": {Type: CurvePiecewise, Points: [][2]float64{{2, 1}, {1, 0}}}}, "greater than previous"},
+		{map[string]Curve{"view": {Type: CurveLinear}}, `curve "view": unknown factor`},
+		{map[string]Curve{"bedrooms": {Type: CurveLinear}}, "does not support curves"},
+	}
+	for _, c := range cases {
+		w := DefaultWeights()
+		w.Curves = c.curves
+		err := w.Validate()
+		if err == nil || !strings.Contains(err.Error(), c.errHas) {
+			t.Fatalf("curves=%v: err=%v want containing %q", c.curves, err, c.errHas)
+		}
+	}
+}
+
+func TestLoadWeightsFromFile_RejectsInvalidCurve(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "weights.json")
+	body := `{"quietness": 0.5, "curves": {"sea_proximity": {"type": "sigmoid", "midpoint": 3}}}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := LoadWeightsFromFile(path)
+	if err == nil {
+		t.Fatalf("expected error for invalid curve")
+	}
+	if w.Quietness != DefaultWeights().Quietness {
+		t.Fatalf("invalid file must fall back to defaults, got quietness=%v", w.Quietness)
+	}
+}
+
+func TestEngine_SeaCurveWithin3km(t *testing.T) {
+	t.Parallel()
+
+	w := DefaultWeights()
+	w.Curves = map[string]Curve{"sea_proximity": {Type: CurveThreshold, At: 3, Below: 1, Above: 0}}
+	e := NewEngine(w)
+
+	profile := domain.ClientProfile{Priorities: domain.PreferenceWeights{SeaProximity: 1}}
+	props := []domain.Property{
+		{ID: "near", Features: domain.Features{DistanceToSeaKm: 2.9}},
+		{ID: "far", Features: domain.Features{DistanceToSeaKm: 3.5}},
+	}
+	res := e.ScoreProperties(profile, props, 2)
+	if res[0].Property.ID != "near" || res[0].Score != 100 || res[1].Score != 0 {
+		t.Fatalf("results=%+v", res)
+	}
+}
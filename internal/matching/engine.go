@@ -1,171 +1,500 @@
 package matching
 
 import (
+	"container/heap"
+	"fmt"
+	"iter"
 	"math"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
 )
 
+// Engine scores properties. Its weights can be swapped at runtime with
+// SetWeights; every scoring call works on one consistent state.
 type Engine struct {
-	weights Weights
+	state       atomic.Pointer[engineState]
+	newRegistry func(Weights) *Registry
+	mu          sync.Mutex        // serializes SetWeights and SetPresets
+	presetDocs  map[string][]byte // guarded by mu; layered over the weights by newState
+	lang        string            // reason language, DefaultLang when empty
 }
 
+// engineState is immutable once stored.
+type engineState struct {
+	weights     Weights
+	weightByKey map[string]float64
+	registry    *Registry
+	presets     map[string]Weights // named presets over weights
+	version     WeightsVersion
+}
+
+// WeightsVersion identifies a set of weights loaded into an engine.
+type WeightsVersion struct {
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewEngine builds an engine with the default factor registry.
 func NewEngine(w Weights) *Engine {
-	return &Engine{weights: w}
+	return newEngine(w, DefaultRegistry, WeightsVersion{Version: 1, UpdatedAt: time.Now().UTC()})
+}
+
+// NewEngineWithRegistry builds an engine scoring with a custom set of factors.
+func NewEngineWithRegistry(w Weights, r *Registry) *Engine {
+	return newEngine(w, func(Weights) *Registry { return r }, WeightsVersion{Version: 1, UpdatedAt: time.Now().UTC()})
+}
+
+func newEngine(w Weights, newRegistry func(Weights) *Registry, v WeightsVersion) *Engine {
+	e := &Engine{newRegistry: newRegistry}
+	st, _ := newState(w, newRegistry, nil, v) // no presets, nothing to drop
+	e.state.Store(st)
+	return e
+}
+
+// newState always returns a usable state; the error names the preset
+// documents that were dropped (see layerPresets).
+func newState(w Weights, newRegistry func(Weights) *Registry, presetDocs map[string][]byte, v WeightsVersion) (*engineState, error) {
+	presets, err := layerPresets(w, presetDocs)
+	return &engineState{
+		weights:     w,
+		weightByKey: weightsByKey(w),
+		registry:    newRegistry(w),
+		presets:     presets,
+		version:     v,
+	}, err
+}
+
+// Weights returns the weights the engine currently scores with.
+func (e *Engine) Weights() Weights {
+	return e.state.Load().weights
+}
+
+// Version returns the current weights version (zero for engines derived
+// with WithWeights).
+func (e *Engine) Version() WeightsVersion {
+	return e.state.Load().version
+}
+
+// Preset returns a named preset layered over the current weights.
+func (e *Engine) Preset(name string) (Weights, bool) {
+	w, ok := e.state.Load().presets[name]
+	return w, ok
+}
+
+// SetWeights atomically swaps in new weights and bumps the version; the
+// presets are layered over them again in the same swap.
+// Scoring calls already in progress keep using the previous weights.
+// Callers are expected to validate w first (see Weights.Validate).
+// w is swapped in either way; the error (ErrPresetDropped) names presets
+// that no longer apply over w and are unavailable until the next swap.
+func (e *Engine) SetWeights(w Weights) (WeightsVersion, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	v := WeightsVersion{Version: e.state.Load().version.Version + 1, UpdatedAt: time.Now().UTC()}
+	st, err := newState(w, e.newRegistry, e.presetDocs, v)
+	e.state.Store(st)
+	return v, err
+}
+
+// SetPresets replaces the named preset documents (see LoadPresetDocs), which
+// every later SetWeights layers over the new weights. The weights version
+// stays the same. The error reports dropped presets as for SetWeights.
+func (e *Engine) SetPresets(docs map[string][]byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.presetDocs = docs
+	cur := e.state.Load()
+	st, err := newState(cur.weights, e.newRegistry, docs, cur.version)
+	e.state.Store(st)
+	return err
+}
+
+// Snapshot returns an engine pinned to the current weights, so a request can
+// read Weights() and score against exactly the same state.
+func (e *Engine) Snapshot() *Engine {
+	s := &Engine{newRegistry: e.newRegistry, lang: e.lang}
+	s.state.Store(e.state.Load())
+	return s
+}
+
+// WithLang returns an engine pinned to the current weights (like Snapshot)
+// that renders reasons in lang (see IsSupportedLang).
+func (e *Engine) WithLang(lang string) *Engine {
+	s := e.Snapshot()
+	s.lang = lang
+	return s
+}
+
+// WithWeights returns a new engine using other weights, e.g. a per-request
+// preset or override. The receiver is not modified.
+func (e *Engine) WithWeights(w Weights) *Engine {
+	out := newEngine(w, e.newRegistry, WeightsVersion{})
+	out.lang = e.lang
+	return out
 }
 
 // ScoreProperties applies hard filters, computes score (0..100), and returns top results.
 func (e *Engine) ScoreProperties(profile domain.ClientProfile, properties []domain.Property, limit int) []domain.ScoreResult {
-	type scored struct {
-		res    domain.ScoreResult
-		reason []domain.ScoreReason
+	out, _ := e.score(profile, properties, limit, false)
+	return out
+}
+
+// ScorePropertiesExplained is ScoreProperties that also reports every property
+// excluded by hard filters together with the constraints it failed.
+func (e *Engine) ScorePropertiesExplained(profile domain.ClientProfile, properties []domain.Property, limit int) ([]domain.ScoreResult, []domain.Rejection) {
+	return e.score(profile, properties, limit, true)
+}
+
+func (e *Engine) score(profile domain.ClientProfile, properties []domain.Property, limit int, explain bool) ([]domain.ScoreResult, []domain.Rejection) {
+	return e.ScoreSeq(profile, slices.Values(properties), limit, explain)
+}
+
+// ScoreSeq scores a stream of properties, e.g. rows read from a database.
+// Only the best limit results are kept in memory; rejections (explain=true)
+// are collected for every filtered-out property.
+func (e *Engine) ScoreSeq(profile domain.ClientProfile, properties iter.Seq[domain.Property], limit int, explain bool) ([]domain.ScoreResult, []domain.Rejection) {
+	st := e.state.Load()
+	var rejected []domain.Rejection
+	priorities := prioritiesByKey(profile.Priorities)
+	if limit <= 0 {
+		limit = 5
 	}
-	var out []domain.ScoreResult
 
-	for _, p := range properties {
-		if !passesHardFilters(profile, p) {
+	top := make(topK, 0, limit)
+	seq := 0
+	for p := range properties {
+		if failures := hardFilterFailures(profile, p); len(failures) > 0 {
+			if explain {
+				rejected = append(rejected, domain.Rejection{
+					PropertyID: p.ID,
+					Title:      p.Title,
+					Failures:   failures,
+				})
+			}
 			continue
 		}
-		score, reasons := e.scoreOne(profile, p)
-		out = append(out, domain.ScoreResult{
-			Property: p,
-			Score:    score,
-			Reasons:  reasons,
-		})
+		r := ranked{seq: seq, res: domain.ScoreResult{Property: p, Score: st.scoreOne(profile, priorities, p)}}
+		seq++
+		switch {
+		case len(top) < limit:
+			heap.Push(&top, r)
+		case worse(top[0], r):
+			top[0] = r
+			heap.Fix(&top, 0)
+		}
 	}
 
-	sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
-	if limit <= 0 {
-		limit = 5
+	sort.Slice(top, func(i, j int) bool { return worse(top[j], top[i]) })
+	out := make([]domain.ScoreResult, len(top))
+	// Reasons are rendered only for the returned results.
+	for i := range top {
+		out[i] = top[i].res
+		out[i].Reasons = st.reasons(profile, priorities, out[i].Property, e.lang)
 	}
-	if len(out) > limit {
-		out = out[:limit]
+	return out, rejected
+}
+
+// ranked is a scored property with its position among the passing ones, so
+// equal scores keep input order.
+type ranked struct {
+	res domain.ScoreResult
+	seq int
+}
+
+func worse(a, b ranked) bool {
+	if a.res.Score != b.res.Score {
+		return a.res.Score < b.res.Score
 	}
-	return out
+	return a.seq > b.seq
+}
+
+// topK is a heap of the best results so far with the worst one at the root.
+type topK []ranked
+
+func (h topK) Len() int           { return len(h) }
+func (h topK) Less(i, j int) bool { return worse(h[i], h[j]) }
+func (h topK) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *topK) Push(x any)        { *h = append(*h, x.(ranked)) }
+func (h *topK) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// RejectionCounts aggregates rejections by failed constraint.
+// A property is counted once per constraint even if it fails it several times
+// (e.g. two missing amenities).
+func RejectionCounts(rejected []domain.Rejection) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range rejected {
+		seen := make(map[string]bool, len(r.Failures))
+		for _, f := range r.Failures {
+			if seen[f.Constraint] {
+				continue
+			}
+			seen[f.Constraint] = true
+			counts[f.Constraint]++
+		}
+	}
+	return counts
 }
 
 func passesHardFilters(profile domain.ClientProfile, p domain.Property) bool {
+	return len(hardFilterFailures(profile, p)) == 0
+}
+
+// hardFilterFailures checks every hard constraint and returns the ones the property fails.
+func hardFilterFailures(profile domain.ClientProfile, p domain.Property) []domain.FilterFailure {
+	hf := profile.HardFilters
+	var out []domain.FilterFailure
+	fail := func(constraint, format string, args ...any) {
+		out = append(out, domain.FilterFailure{Constraint: constraint, Message: fmt.Sprintf(format, args...)})
+	}
+
 	// Budget hard filter (if set)
 	if profile.BudgetMin > 0 && p.Price < profile.BudgetMin {
-		return false
+		fail("budget_min", "price %s < budget_min %s", fmtNum(p.Price), fmtNum(profile.BudgetMin))
 	}
 	if profile.BudgetMax > 0 && p.Price > profile.BudgetMax {
-		return false
+		fail("budget_max", "price %s > budget_max %s", fmtNum(p.Price), fmtNum(profile.BudgetMax))
 	}
-	// Required amenities
-	if len(profile.HardFilters.MustHaveAmenities) > 0 {
-		have := make(map[string]struct{}, len(p.Amenities))
-		for _, a := range p.Amenities {
-			have[strings.ToLower(strings.TrimSpace(a))] = struct{}{}
-		}
-		for _, req := range profile.HardFilters.MustHaveAmenities {
-			r := strings.ToLower(strings.TrimSpace(req))
-			if r == "" {
-				continue
-			}
-			if _, ok := have[r]; !ok {
-				return false
-			}
-		}
+
+	// Rooms and area (0 = not set)
+	if hf.MinBedrooms > 0 && p.Bedrooms < hf.MinBedrooms {
+		fail("min_bedrooms", "bedrooms %d < min_bedrooms %d", p.Bedrooms, hf.MinBedrooms)
+	}
+	if hf.MaxBedrooms > 0 && p.Bedrooms > hf.MaxBedrooms {
+		fail("max_bedrooms", "bedrooms %d > max_bedrooms %d", p.Bedrooms, hf.MaxBedrooms)
+	}
+	if hf.MinBathrooms > 0 && p.Bathrooms < hf.MinBathrooms {
+		fail("min_bathrooms", "bathrooms %d < min_bathrooms %d", p.Bathrooms, hf.MinBathrooms)
+	}
+	if hf.MaxBathrooms > 0 && p.Bathrooms > hf.MaxBathrooms {
+		fail("max_bathrooms", "bathrooms %d > max_bathrooms %d", p.Bathrooms, hf.MaxBathrooms)
+	}
+	if hf.MinAreaSQM > 0 && p.AreaSQM < hf.MinAreaSQM {
+		fail("min_area_sqm", "area_sqm %s < min_area_sqm %s", fmtNum(p.AreaSQM), fmtNum(hf.MinAreaSQM))
+	}
+	if hf.MaxAreaSQM > 0 && p.AreaSQM > hf.MaxAreaSQM {
+		fail("max_area_sqm", "area_sqm %s > max_area_sqm %s", fmtNum(p.AreaSQM), fmtNum(hf.MaxAreaSQM))
 	}
-	return true
-}
 
-func (e *Engine) scoreOne(profile domain.ClientProfile, p domain.Property) (float64, []domain.ScoreReason) {
-	// Soft factors: we compute weighted contributions in 0..1, then scale to 0..100.
-	type factor struct {
-		key      string
-		label    string
-		wantHigh bool // true: higher is better; false: lower is better
-		weight   float64
-		pref     float64
-		value    float64
+	// Locations: contains, case-insensitive (same as location preference)
+	if len(normalizeList(hf.AllowedLocations)) > 0 && !locationMatchesAny(p.Location, hf.AllowedLocations) {
+		fail("allowed_locations", "location %q not in allowed_locations", p.Location)
+	}
+	if locationMatchesAny(p.Location, hf.BlockedLocations) {
+		fail("blocked_locations", "location %q is blocked", p.Location)
 	}
 
-	factors := []factor{
-		{"quietness", "quietness", true, e.weights.Quietness, profile.Priorities.Quietness, clamp01(p.Features.Quietness)},
-		{"sun_exposure", "sun exposure", true, e.weights.SunExposure, profile.Priorities.SunExposure, clamp01(p.Features.SunExposure)},
-		{"wind_protection", "wind protection", true, e.weights.WindProtection, profile.Priorities.WindProtection, clamp01(p.Features.WindProtection)},
-		{"low_tourism", "low tourism", false, e.weights.LowTourism, profile.Priorities.LowTourism, clamp01(p.Features.TourismIntensity)},
-		{"family_friendliness", "family friendly", true, e.weights.FamilyFriendliness, profile.Priorities.FamilyFriendliness, clamp01(p.Features.FamilyFriendly)},
-		{"expat_community", "expat friendly", true, e.weights.ExpatCommunity, profile.Priorities.ExpatCommunity, clamp01(p.Features.ExpatFriendly)},
-		{"investment_focus", "investment potential", true, e.weights.InvestmentFocus, profile.Priorities.InvestmentFocus, clamp01(p.Features.InvestmentPotential)},
-		{"walkability", "walkability", true, e.weights.Walkability, profile.Priorities.Walkability, clamp01(p.Features.Walkability)},
-		{"green_areas", "green areas", true, e.weights.GreenAreas, profile.Priorities.GreenAreas, clamp01(p.Features.GreenAreas)},
-		{"sea_proximity", "sea proximity", true, e.weights.SeaProximity, profile.Priorities.SeaProximity, seaProximity01(p.Features.DistanceToSeaKm)},
+	if hf.MaxDistanceToSeaKm > 0 && p.Features.DistanceToSeaKm > hf.MaxDistanceToSeaKm {
+		fail("max_distance_to_sea_km", "distance_to_sea_km %s > max_distance_to_sea_km %s",
+			fmtNum(p.Features.DistanceToSeaKm), fmtNum(hf.MaxDistanceToSeaKm))
 	}
 
-	var sumW, sum float64
-	var contributions []domain.ScoreReason
+	// Amenities
+	have := amenitySet(p.Amenities)
+	for _, r := range normalizeList(hf.MustHaveAmenities) {
+		if _, ok := have[r]; !ok {
+			fail("must_have_amenities", "missing amenity: %s", r)
+		}
+	}
+	for _, r := range normalizeList(hf.ExcludedAmenities) {
+		if _, ok := have[r]; ok {
+			fail("excluded_amenities", "excluded amenity: %s", r)
+		}
+	}
 
-	for _, f := range factors {
-		// If client doesn't care about this factor, skip it.
-		if f.pref <= 0 || f.weight <= 0 {
+	// Named feature minimums; an unknown feature name can never be satisfied.
+	keys := make([]string, 0, len(hf.MinFeatures))
+	for k := range hf.MinFeatures {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		min := hf.MinFeatures[key]
+		v, ok := domain.FeatureValue(p.Features, strings.ToLower(strings.TrimSpace(key)))
+		if !ok {
+			fail("min_features", "unknown feature: %s", key)
 			continue
 		}
-		w := f.pref * f.weight
-		sumW += w
-
-		v := f.value
-		if !f.wantHigh {
-			// For "low tourism", lower tourism_intensity is better: invert.
-			v = 1 - v
+		if v < min {
+			fail("min_features", "%s %s < min %s", key, fmtNum(v), fmtNum(min))
 		}
-		contrib := w * v
-		sum += contrib
-
-		contributions = append(contributions, domain.ScoreReason{
-			Type:    f.key,
-			Message: reasonMessage(f.label, v),
-			Impact:  contrib,
-		})
 	}
+	return out
+}
 
-	// Soft nudge: budget closeness to max (if set). Adds up to 0.05 of total.
-	if profile.BudgetMax > 0 && sumW > 0 {
-		close01 := budgetCloseness01(p.Price, profile.BudgetMax)
-		w := 0.05 * sumW
-		sumW += w
-		sum += w * close01
-		contributions = append(contributions, domain.ScoreReason{
-			Type:    "budget_closeness",
-			Message: reasonMessage("budget closeness", close01),
-			Impact:  w * close01,
-		})
+// fmtNum prints a number without exponent or trailing zeros (450000, 1.5).
+func fmtNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func amenitySet(amenities []string) map[string]struct{} {
+	have := make(map[string]struct{}, len(amenities))
+	for _, a := range amenities {
+		have[strings.ToLower(strings.TrimSpace(a))] = struct{}{}
 	}
+	return have
+}
 
-       	// Soft nudge: location preference (if set). Adds up to 0.05 of total.
-	if strings.TrimSpace(profile.LocationPreference) != "" && sumW > 0 {
-		want := strings.ToLower(strings.TrimSpace(profile.LocationPreference))
-		have := strings.ToLower(strings.TrimSpace(p.Location))
+// normalizeList lowercases and trims items, dropping empty ones.
+func normalizeList(items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, it := range items {
+		v := strings.ToLower(strings.TrimSpace(it))
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
 
-		match01 := 0.0
-		if want != "" && have != "" && strings.Contains(have, want) {
-			match01 = 1.0
+func locationMatchesAny(location string, wanted []string) bool {
+	have := strings.ToLower(strings.TrimSpace(location))
+	if have == "" {
+		return false
+	}
+	for _, w := range normalizeList(wanted) {
+		if strings.Contains(have, w) {
+			return true
 		}
+	}
+	return false
+}
+
+func (st *engineState) scoreOne(profile domain.ClientProfile, priorities map[string]float64, p domain.Property) float64 {
+	_, sumW, sum := st.evaluate(profile, priorities, p, false)
+	// If no weights are active, score is neutral 50.
+	if sumW <= 0 {
+		return 50.0
+	}
+	return finalScore(sum, sumW)
+}
 
-		w := 0.05 * sumW
-		sumW += w
-		sum += w * match01
+// reasons returns the top contributions for p with localized messages.
+func (st *engineState) reasons(profile domain.ClientProfile, priorities map[string]float64, p domain.Property, lang string) []domain.ScoreReason {
+	factors, sumW, _ := st.evaluate(profile, priorities, p, false)
 
+	contributions := make([]domain.ScoreReason, 0, len(factors))
+	for _, f := range factors {
 		contributions = append(contributions, domain.ScoreReason{
-			Type:    "location_match",
-			Message: reasonMessage("location preference", match01),
-			Impact:  w * match01,
+			Type:   f.Key,
+			Impact: f.Contribution,
 		})
 	}
-
-	// If no weights are active, score is neutral 50.
+	max := 7
 	if sumW <= 0 {
-		return 50.0, topReasons(contributions, 5)
+		max = 5
 	}
+	top := topReasons(contributions, max)
 
+	for i := range top {
+		for _, fb := range factors {
+			if fb.Key == top[i].Type {
+				top[i].Message = st.message(lang, profile, p, fb)
+				break
+			}
+		}
+	}
+	return top
+}
+
+// message renders the reason text for an evaluated factor.
+func (st *engineState) message(lang string, profile domain.ClientProfile, p domain.Property, fb domain.FactorBreakdown) string {
+	f, ok := st.registry.Get(fb.Key)
+	if !ok {
+		return fb.Label
+	}
+	return renderReason(lang, f, profile, p, fb.Value, fb.Raw)
+}
+
+func finalScore(sum, sumW float64) float64 {
 	score01 := sum / sumW
 	score := math.Round(score01*1000) / 10 // 0.1 precision
+	return clamp(score, 0, 100)
+}
 
-	return clamp(score, 0, 100), topReasons(contributions, 7)
+// evaluate computes every factor's contribution. Soft factors give weighted
+// contributions in 0..1 that are later scaled to 0..100. With all=false only
+// active factors are returned; with all=true inactive ones are included too
+// (Active=false, zero contribution) for the full breakdown.
+func (st *engineState) evaluate(profile domain.ClientProfile, priorities map[string]float64, p domain.Property, all bool) ([]domain.FactorBreakdown, float64, float64) {
+	var sumW, sum float64
+	var out []domain.FactorBreakdown
+	var nudges []Nudge
+
+	eval := func(f Factor, kind string, weight, pref, w float64, active bool) {
+		if !active && !all {
+			return
+		}
+		fb := domain.FactorBreakdown{
+			Key:      f.Key(),
+			Label:    f.Label(),
+			Kind:     kind,
+			Active:   active,
+			Weight:   weight,
+			Priority: pref,
+		}
+
+		curve, hasCurve := st.weights.Curves[f.Key()]
+		rv, hasRaw := f.(rawValuer)
+		if hasRaw {
+			raw := rv.Raw(profile, p)
+			fb.Raw = &raw
+		}
+		if hasCurve && hasRaw {
+			// A configured curve fully defines the 0..1 score, no inversion.
+			fb.Value = curve.Apply(*fb.Raw)
+			fb.Curve = curve.Type
+		} else {
+			fb.Value = f.Value(profile, p)
+			if !f.HigherIsBetter() {
+				// For "low tourism", lower tourism_intensity is better: invert.
+				fb.Value = 1 - fb.Value
+			}
+		}
+
+		if active {
+			fb.EffectiveWeight = w
+			fb.Contribution = w * fb.Value
+			sumW += w
+			sum += fb.Contribution
+		}
+		out = append(out, fb)
+	}
+
+	for _, f := range st.registry.Factors() {
+		if n, ok := f.(Nudge); ok {
+			nudges = append(nudges, n)
+			continue
+		}
+		weight := st.weightByKey[f.Key()]
+		if wk, ok := f.(weightKeyer); ok {
+			weight = st.weightByKey[wk.WeightKey()]
+		}
+		pref := priorities[f.Key()]
+		if pf, ok := f.(prioritizer); ok {
+			pref = pf.Priority(profile)
+		}
+		// If client doesn't care about this factor, skip it.
+		eval(f, "factor", weight, pref, pref*weight, pref > 0 && weight > 0)
+	}
+
+	// Soft nudges (budget closeness, location preference): each adds up to its share of total.
+	for _, n := range nudges {
+		eval(n, "nudge", n.Share(), 0, n.Share()*sumW, n.Active(profile) && sumW > 0)
+	}
+
+	return out, sumW, sum
 }
 
 func topReasons(reasons []domain.ScoreReason, max int) []domain.ScoreReason {
@@ -190,19 +519,6 @@ func topReasons(reasons []domain.ScoreReason, max int) []domain.ScoreReason {
 	return reasons
 }
 
-func reasonMessage(label string, v float64) string {
-	switch {
-	case v >= 0.8:
-		return label + ": strong match"
-	case v >= 0.6:
-		return label + ": good"
-	case v >= 0.4:
-		return label + ": mixed"
-	default:
-		return label + ": weak"
-	}
-}
-
 func clamp01(v float64) float64 {
 	if v < 0 {
 		return 0
@@ -223,6 +539,29 @@ func seaProximity01(distanceKm float64) float64 {
 	return clamp01(v)
 }
 
+// roomFit01 maps a room count mismatch to 0..1 via the tolerance curve:
+// exact match => 1.0, otherwise 1 - penalty for the size of the gap. An
+// empty curve falls back to defaultRoomTolerance.
+func roomFit01(have, want int, t RoomTolerance) float64 {
+	diff := have - want
+	if diff == 0 {
+		return 1
+	}
+	curve, fallback := t.Extra, defaultRoomTolerance.Extra
+	if diff < 0 {
+		diff = -diff
+		curve, fallback = t.Short, defaultRoomTolerance.Short
+	}
+	if len(curve) == 0 {
+		curve = fallback
+	}
+	i := diff - 1
+	if i >= len(curve) {
+		i = len(curve) - 1
+	}
+	return clamp01(1 - curve[i])
+}
+
 func budgetCloseness01(price, budgetMax float64) float64 {
 	if budgetMax <= 0 {
 		return 0.5
@@ -237,3 +576,35 @@ func budgetCloseness01(price, budgetMax float64) float64 {
 func clamp(v, lo, hi float64) float64 {
 	return math.Max(lo, math.Min(hi, v))
 }
+
+// Explain returns every factor's contribution to the property's score,
+// including inactive factors and the budget/location nudges, without the
+// truncation and rescaling applied to ScoreResult reasons.
+func (e *Engine) Explain(profile domain.ClientProfile, p domain.Property) domain.ScoreBreakdown {
+	st := e.state.Load()
+	factors, sumW, sum := st.evaluate(profile, prioritiesByKey(profile.Priorities), p, true)
+
+	failures := hardFilterFailures(profile, p)
+	out := domain.ScoreBreakdown{
+		PropertyID:         p.ID,
+		Score:              50,
+		PassesHardFilters:  len(failures) == 0,
+		HardFilterFailures: failures,
+		TotalWeight:        sumW,
+		Factors:            factors,
+	}
+	if sumW > 0 {
+		out.Score = finalScore(sum, sumW)
+	}
+	for i := range out.Factors {
+		f := &out.Factors[i]
+		f.Message = st.message(e.lang, profile, p, *f)
+		if sumW > 0 {
+			f.Points = f.Contribution / sumW * 100
+		}
+		if sum > 0 {
+			f.Share = f.Contribution / sum
+		}
+	}
+	return out
+}
@@ -0,0 +1,291 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestScoreProperties_RoomFit(t *testing.T) {
+	t.Parallel()
+
+	e := NewEngine(DefaultWeights())
+	profile := domain.ClientProfile{
+		DesiredBedrooms:  3,
+		DesiredBathrooms: 2,
+		Priorities:       domain.PreferenceWeights{Quietness: 0.5},
+	}
+	feat := domain.Features{Quietness: 0.7}
+	props := []domain.Property{
+		{ID: "two", Bedrooms: 2, Bathrooms: 2, Features: feat},
+		{ID: "four", Bedrooms: 4, Bathrooms: 2, Features: feat},
+		{ID: "three", Bedrooms: 3, Bathrooms: 2, Features: feat},
+	}
+
+	res := e.ScoreProperties(profile, props, 10)
+	if len(res) != 3 {
+		t.Fatalf("results=%d want=3", len(res))
+	}
+	order := []string{res[0].Property.ID, res[1].Property.ID, res[2].Property.ID}
+	want := []string{"three", "four", "two"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order=%v want=%v", order, want)
+		}
+	}
+
+	found := false
+	for _, r := range res[0].Reasons {
+		if r.Type == "bedrooms" {
+			found = true
+			if r.Message != "3 bedrooms as requested" {
+				t.Fatalf("message=%q", r.Message)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("no bedrooms reason in %+v", res[0].Reasons)
+	}
+}
+
+func TestRoomFit01(t *testing.T) {
+	t.Parallel()
+
+	tol := RoomTolerance{Short: []float64{0.5, 0.9}, Extra: []float64{0.2}}
+	cases := []struct {
+		have, want int
+		exp        float64
+	}{
+		{3, 3, 1},
+		{2, 3, 0.5},
+		{1, 3, 0.1},
+		{0, 3, 0.1},
+		{4, 3, 0.8},
+		{6, 3, 0.8},
+	}
+	for _, c := range cases {
+		got := roomFit01(c.have, c.want, tol)
+		if diff := got - c.exp; diff > 1e-9 || diff < -1e-9 {
+			t.Fatalf("roomFit01(%d,%d)=%v want=%v", c.have, c.want, got, c.exp)
+		}
+	}
+}
+
+func TestScoreProperties_RoomFitWithoutTolerance(t *testing.T) {
+	t.Parallel()
+
+	// weights with no room_tolerance at all: a one-room miss must still
+	// beat a two-room miss instead of both scoring 0
+	w := DefaultWeights()
+	w.RoomTolerance = RoomTolerance{}
+	if got := roomFit01(2, 3, w.RoomTolerance); got != 0.5 {
+		t.Fatalf("roomFit01(2,3) without tolerance=%v want 0.5", got)
+	}
+	if got := roomFit01(4, 3, RoomTolerance{Short: []float64{1}}); got != 0.85 {
+		t.Fatalf("empty extra curve=%v want 0.85", got)
+	}
+
+	profile := domain.ClientProfile{DesiredBedrooms: 3}
+	res := NewEngine(w).ScoreProperties(profile, []domain.Property{
+		{ID: "one", Bedrooms: 1},
+		{ID: "two", Bedrooms: 2},
+	}, 10)
+	if len(res) != 2 || res[0].Property.ID != "two" || res[0].Score <= res[1].Score {
+		t.Fatalf("results=%+v", res)
+	}
+}
+
+func TestPassesHardFilters(t *testing.T) {
+	t.Parallel()
+
+	p := domain.Property{
+		ID:        "es-001",
+		Location:  "Valencia, Ruzafa",
+		Price:     320000,
+		Bedrooms:  3,
+		Bathrooms: 2,
+		AreaSQM:   110,
+		Amenities: []string{"Balcony", "parking", "ground_floor"},
+		Features:  domain.Features{Quietness: 0.6, DistanceToSeaKm: 1.5},
+	}
+
+	cases := []struct {
+		name string
+		hf   domain.HardFilters
+		want bool
+	}{
+		{"empty", domain.HardFilters{}, true},
+		{"must_have ok", domain.HardFilters{MustHaveAmenities: []string{" balcony "}}, true},
+		{"must_have missing", domain.HardFilters{MustHaveAmenities: []string{"elevator"}}, false},
+		{"excluded present", domain.HardFilters{ExcludedAmenities: []string{"Ground_Floor"}}, false},
+		{"excluded absent", domain.HardFilters{ExcludedAmenities: []string{"pool"}}, true},
+		{"min_bedrooms ok", domain.HardFilters{MinBedrooms: 3}, true},
+		{"min_bedrooms fail", domain.HardFilters{MinBedrooms: 4}, false},
+		{"max_bedrooms ok", domain.HardFilters{MaxBedrooms: 3}, true},
+		{"max_bedrooms fail", domain.HardFilters{MaxBedrooms: 2}, false},
+		{"min_bathrooms fail", domain.HardFilters{MinBathrooms: 3}, false},
+		{"max_bathrooms fail", domain.HardFilters{MaxBathrooms: 1}, false},
+		{"min_area ok", domain.HardFilters{MinAreaSQM: 100}, true},
+		{"min_area fail", domain.HardFilters{MinAreaSQM: 120}, false},
+		{"max_area fail", domain.HardFilters{MaxAreaSQM: 100}, false},
+		{"allowed ok", domain.HardFilters{AllowedLocations: []string{"Madrid", "valencia"}}, true},
+		{"allowed fail", domain.HardFilters{AllowedLocations: []string{"Madrid"}}, false},
+		{"blocked hit", domain.HardFilters{BlockedLocations: []string{"ruzafa"}}, false},
+		{"blocked miss", domain.HardFilters{BlockedLocations: []string{"Alicante"}}, true},
+		{"sea ok", domain.HardFilters{MaxDistanceToSeaKm: 2}, true},
+		{"sea fail", domain.HardFilters{MaxDistanceToSeaKm: 1}, false},
+		{"min feature ok", domain.HardFilters{MinFeatures: map[string]float64{"quietness": 0.6}}, true},
+		{"min feature fail", domain.HardFilters{MinFeatures: map[string]float64{"quietness": 0.7}}, false},
+		{"min feature unknown", domain.HardFilters{MinFeatures: map[string]float64{"view": 0.1}}, false},
+	}
+	for _, c := range cases {
+		profile := domain.ClientProfile{HardFilters: c.hf}
+		if got := passesHardFilters(profile, p); got != c.want {
+			t.Fatalf("%s: passes=%v want=%v", c.name, got, c.want)
+		}
+	}
+
+	budget := []struct {
+		min, max float64
+		want     bool
+	}{
+		{300000, 400000, true},
+		{330000, 0, false},
+		{0, 300000, false},
+	}
+	for _, b := range budget {
+		profile := domain.ClientProfile{BudgetMin: b.min, BudgetMax: b.max}
+		if got := passesHardFilters(profile, p); got != b.want {
+			t.Fatalf("budget %v..%v: passes=%v want=%v", b.min, b.max, got, b.want)
+		}
+	}
+}
+
+func TestScorePropertiesExplained_Rejections(t *testing.T) {
+	t.Parallel()
+
+	e := NewEngine(DefaultWeights())
+	profile := domain.ClientProfile{
+		BudgetMax:   400000,
+		HardFilters: domain.HardFilters{MustHaveAmenities: []string{"parking", "elevator"}},
+	}
+	props := []domain.Property{
+		{ID: "ok", Price: 300000, Amenities: []string{"parking", "elevator"}},
+		{ID: "pricey", Price: 450000, Amenities: []string{"elevator"}},
+		{ID: "bare", Price: 200000},
+	}
+
+	res, rejected := e.ScorePropertiesExplained(profile, props, 5)
+	if len(res) != 1 || res[0].Property.ID != "ok" {
+		t.Fatalf("results=%+v", res)
+	}
+	if len(rejected) != 2 {
+		t.Fatalf("rejected=%d want=2", len(rejected))
+	}
+
+	pricey := rejected[0]
+	if pricey.PropertyID != "pricey" || len(pricey.Failures) != 2 {
+		t.Fatalf("pricey=%+v", pricey)
+	}
+	if pricey.Failures[0].Message != "price 450000 > budget_max 400000" {
+		t.Fatalf("message=%q", pricey.Failures[0].Message)
+	}
+	if pricey.Failures[1].Message != "missing amenity: parking" {
+		t.Fatalf("message=%q", pricey.Failures[1].Message)
+	}
+
+	counts := RejectionCounts(rejected)
+	if counts["budget_max"] != 1 || counts["must_have_amenities"] != 2 {
+		t.Fatalf("counts=%v", counts)
+	}
+}
+
+func TestExplain_MatchesScore(t *testing.T) {
+	t.Parallel()
+
+	e := NewEngine(DefaultWeights())
+	profile := domain.ClientProfile{
+		LocationPreference: "Valencia",
+		BudgetMax:          400000,
+		DesiredBedrooms:    3,
+		Priorities:         domain.PreferenceWeights{Quietness: 0.3, SunExposure: 0.2, LowTourism: 0.1},
+	}
+	p := domain.Property{
+		ID: "es-001", Location: "Valencia", Price: 320000, Bedrooms: 3,
+		Features: domain.Features{Quietness: 0.6, SunExposure: 0.85, TourismIntensity: 0.3, DistanceToSeaKm: 1.5},
+	}
+
+	b := e.Explain(profile, p)
+	want := e.ScoreProperties(profile, []domain.Property{p}, 1)[0].Score
+	if b.Score != want || !b.PassesHardFilters {
+		t.Fatalf("breakdown score=%v passes=%v want score=%v", b.Score, b.PassesHardFilters, want)
+	}
+
+	var points, share float64
+	byKey := map[string]domain.FactorBreakdown{}
+	for _, f := range b.Factors {
+		points += f.Points
+		share += f.Share
+		byKey[f.Key] = f
+	}
+	if math.Abs(points-b.Score) > 0.05 || math.Abs(share-1) > 1e-9 {
+		t.Fatalf("points=%v share=%v score=%v", points, share, b.Score)
+	}
+
+	// every registered factor is listed, including inactive ones
+	if len(b.Factors) != len(DefaultRegistry(DefaultWeights()).Factors()) {
+		t.Fatalf("factors=%d", len(b.Factors))
+	}
+	if f := byKey["sea_proximity"]; f.Active || f.Raw == nil || *f.Raw != 1.5 {
+		t.Fatalf("sea_proximity=%+v", f)
+	}
+	if f := byKey["low_tourism"]; !f.Active || *f.Raw != 0.3 || math.Abs(f.Value-0.7) > 1e-9 || f.Priority != 0.1 || f.Weight != 1.0 {
+		t.Fatalf("low_tourism=%+v", f)
+	}
+	for _, k := range []string{"budget_closeness", "location_match"} {
+		if f := byKey[k]; f.Kind != "nudge" || !f.Active || f.Contribution <= 0 {
+			t.Fatalf("%s=%+v", k, f)
+		}
+	}
+}
+
+func TestScoreSeq_TopKMatchesFullSort(t *testing.T) {
+	t.Parallel()
+
+	var props []domain.Property
+	for i := 0; i < 200; i++ {
+		props = append(props, domain.Property{
+			ID:       fmt.Sprintf("p-%d", i),
+			Price:    float64(100000 + (i*7919)%300000),
+			Features: domain.Features{Quietness: float64((i*31)%10) / 10, Walkability: float64((i*17)%10) / 10},
+		})
+	}
+	profile := domain.ClientProfile{BudgetMax: 350000, Priorities: domain.PreferenceWeights{Quietness: 1, Walkability: 0.5}}
+	e := NewEngine(DefaultWeights())
+
+	all := e.ScoreProperties(profile, props, len(props))
+	top := e.ScoreProperties(profile, props, 7)
+	if len(top) != 7 {
+		t.Fatalf("len=%d", len(top))
+	}
+	for i := range top {
+		if top[i].Property.ID != all[i].Property.ID || top[i].Score != all[i].Score {
+			t.Fatalf("#%d: %s %.2f, full sort has %s %.2f", i, top[i].Property.ID, top[i].Score, all[i].Property.ID, all[i].Score)
+		}
+	}
+	// equal scores keep input order
+	for i := 1; i < len(all); i++ {
+		if all[i].Score == all[i-1].Score && idNum(all[i].Property.ID) < idNum(all[i-1].Property.ID) {
+			t.Fatalf("tie order: %s before %s", all[i-1].Property.ID, all[i].Property.ID)
+		}
+	}
+}
+
+func idNum(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "p-"))
+	return n
+}
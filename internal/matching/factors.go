@@ -0,0 +1,273 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// Factor is one scoring dimension. The engine weights it by the system weight
+// and the client priority stored under Key() in Weights / PreferenceWeights.
+type Factor interface {
+	Key() string
+	Label() string
+	// Value extracts a raw 0..1 value; the profile is there for factors that
+	// are relative to the client (budget, location, rooms).
+	Value(profile domain.ClientProfile, p domain.Property) float64
+	// HigherIsBetter is false when the value must be inverted (1 - v).
+	HigherIsBetter() bool
+}
+
+// Nudge is a factor without a client priority: when Active it adds Share() of
+// the weight accumulated by the regular factors. Nudges are applied last.
+type Nudge interface {
+	Factor
+	Active(profile domain.ClientProfile) bool
+	Share() float64
+}
+
+// Optional Factor extensions.
+type (
+	// prioritizer overrides the client priority lookup by key.
+	prioritizer interface {
+		Priority(profile domain.ClientProfile) float64
+	}
+	// weightKeyer makes the factor use another key's system weight.
+	weightKeyer interface {
+		WeightKey() string
+	}
+	// rawValuer exposes the untransformed value; only such factors accept a Curve.
+	rawValuer interface {
+		Raw(profile domain.ClientProfile, p domain.Property) float64
+	}
+)
+
+// Registry holds factors in registration order.
+type Registry struct {
+	factors []Factor
+	byKey   map[string]Factor
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byKey: make(map[string]Factor)}
+}
+
+// Register adds a factor; keys must be unique.
+func (r *Registry) Register(f Factor) error {
+	key := f.Key()
+	if key == "" {
+		return fmt.Errorf("factor key is empty")
+	}
+	if _, ok := r.byKey[key]; ok {
+		return fmt.Errorf("factor %q already registered", key)
+	}
+	r.byKey[key] = f
+	r.factors = append(r.factors, f)
+	return nil
+}
+
+// MustRegister is Register that panics on error, for static setup.
+func (r *Registry) MustRegister(fs ...Factor) {
+	for _, f := range fs {
+		if err := r.Register(f); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Factors returns registered factors in order.
+func (r *Registry) Factors() []Factor {
+	return append([]Factor(nil), r.factors...)
+}
+
+// Get returns a factor by key.
+func (r *Registry) Get(key string) (Factor, bool) {
+	f, ok := r.byKey[key]
+	return f, ok
+}
+
+// DefaultRegistry registers the built-in factors: ten property features,
+// room fit, and the budget/location nudges.
+func DefaultRegistry(w Weights) *Registry {
+	r := NewRegistry()
+	r.MustRegister(
+		FeatureFactor("quietness", "quietness", true, func(f domain.Features) float64 { return f.Quietness }),
+		FeatureFactor("sun_exposure", "sun exposure", true, func(f domain.Features) float64 { return f.SunExposure }),
+		FeatureFactor("wind_protection", "wind protection", true, func(f domain.Features) float64 { return f.WindProtection }),
+		FeatureFactor("low_tourism", "low tourism", false, func(f domain.Features) float64 { return f.TourismIntensity }),
+		FeatureFactor("family_friendliness", "family friendly", true, func(f domain.Features) float64 { return f.FamilyFriendly }),
+		FeatureFactor("expat_community", "expat friendly", true, func(f domain.Features) float64 { return f.ExpatFriendly }),
+		FeatureFactor("investment_focus", "investment potential", true, func(f domain.Features) float64 { return f.InvestmentPotential }),
+		FeatureFactor("walkability", "walkability", true, func(f domain.Features) float64 { return f.Walkability }),
+		FeatureFactor("green_areas", "green areas", true, func(f domain.Features) float64 { return f.GreenAreas }),
+		&featureFactor{key: "sea_proximity", label: "sea proximity", higher: true,
+			raw: func(f domain.Features) float64 { return f.DistanceToSeaKm }, transform: seaProximity01},
+		&roomFactor{key: "bedrooms", tol: w.RoomTolerance,
+			want: func(c domain.ClientProfile) int { return c.DesiredBedrooms },
+			have: func(p domain.Property) int { return p.Bedrooms }},
+		&roomFactor{key: "bathrooms", tol: w.RoomTolerance,
+			want: func(c domain.ClientProfile) int { return c.DesiredBathrooms },
+			have: func(p domain.Property) int { return p.Bathrooms }},
+		budgetNudge{},
+		locationNudge{},
+	)
+	return r
+}
+
+// featureFactor reads one value from domain.Features.
+type featureFactor struct {
+	key, label string
+	higher     bool
+	raw        func(domain.Features) float64
+	transform  func(float64) float64 // raw -> 0..1; clamp01 when nil
+}
+
+// FeatureFactor builds a factor from a domain.Features accessor whose value is
+// already on a 0..1 scale.
+func FeatureFactor(key, label string, higherIsBetter bool, raw func(domain.Features) float64) Factor {
+	return &featureFactor{key: key, label: label, higher: higherIsBetter, raw: raw}
+}
+
+func (f *featureFactor) Key() string          { return f.key }
+func (f *featureFactor) Label() string        { return f.label }
+func (f *featureFactor) HigherIsBetter() bool { return f.higher }
+func (f *featureFactor) Raw(_ domain.ClientProfile, p domain.Property) float64 {
+	return f.raw(p.Features)
+}
+func (f *featureFactor) Value(c domain.ClientProfile, p domain.Property) float64 {
+	if f.transform != nil {
+		return f.transform(f.Raw(c, p))
+	}
+	return clamp01(f.Raw(c, p))
+}
+
+// roomFactor scores a room count against the desired one (see roomFit01).
+// It is active whenever the desired count is set and shares the room_fit weight.
+type roomFactor struct {
+	key  string
+	tol  RoomTolerance
+	want func(domain.ClientProfile) int
+	have func(domain.Property) int
+}
+
+func (f *roomFactor) Key() string          { return f.key }
+func (f *roomFactor) Label() string        { return f.key }
+func (f *roomFactor) HigherIsBetter() bool { return true }
+func (f *roomFactor) WeightKey() string    { return "room_fit" }
+func (f *roomFactor) Value(c domain.ClientProfile, p domain.Property) float64 {
+	return roomFit01(f.have(p), f.want(c), f.tol)
+}
+func (f *roomFactor) Priority(c domain.ClientProfile) float64 {
+	if f.want(c) > 0 {
+		return 1
+	}
+	return 0
+}
+func (f *roomFactor) fillReason(d *reasonData, c domain.ClientProfile, p domain.Property) {
+	d.Have, d.Want = f.have(p), f.want(c)
+	d.Diff = d.Have - d.Want
+	if d.Diff < 0 {
+		d.Diff = -d.Diff
+	}
+}
+
+// budgetNudge prefers prices comfortably below budget_max.
+type budgetNudge struct{}
+
+func (budgetNudge) Key() string          { return "budget_closeness" }
+func (budgetNudge) Label() string        { return "budget closeness" }
+func (budgetNudge) HigherIsBetter() bool { return true }
+func (budgetNudge) Share() float64       { return 0.05 }
+func (budgetNudge) Active(c domain.ClientProfile) bool {
+	return c.BudgetMax > 0
+}
+func (budgetNudge) Value(c domain.ClientProfile, p domain.Property) float64 {
+	return budgetCloseness01(p.Price, c.BudgetMax)
+}
+func (budgetNudge) fillReason(d *reasonData, c domain.ClientProfile, p domain.Property) {
+	d.Price, d.BudgetMax = p.Price, c.BudgetMax
+	if c.BudgetMax > 0 {
+		d.PctBelow = int(math.Round((1 - p.Price/c.BudgetMax) * 100))
+	}
+}
+
+// locationNudge rewards a location containing the preferred one.
+type locationNudge struct{}
+
+func (locationNudge) Key() string          { return "location_match" }
+func (locationNudge) Label() string        { return "location preference" }
+func (locationNudge) HigherIsBetter() bool { return true }
+func (locationNudge) Share() float64       { return 0.05 }
+func (locationNudge) Active(c domain.ClientProfile) bool {
+	return strings.TrimSpace(c.LocationPreference) != ""
+}
+func (n locationNudge) fillReason(d *reasonData, c domain.ClientProfile, p domain.Property) {
+	d.Location = strings.TrimSpace(p.Location)
+	d.Preferred = strings.TrimSpace(c.LocationPreference)
+	d.Matched = n.Value(c, p) == 1
+}
+func (locationNudge) Value(c domain.ClientProfile, p domain.Property) float64 {
+	want := strings.ToLower(strings.TrimSpace(c.LocationPreference))
+	have := strings.ToLower(strings.TrimSpace(p.Location))
+	if want != "" && have != "" && strings.Contains(have, want) {
+		return 1
+	}
+	return 0
+}
+
+// weightFields and priorityFields list the numeric fields of Weights and
+// domain.PreferenceWeights by JSON key, which is the factor (or weight) key
+// they apply to.
+var weightFields = []struct {
+	key string
+	get func(Weights) float64
+}{
+	{"quietness", func(w Weights) float64 { return w.Quietness }},
+	{"sun_exposure", func(w Weights) float64 { return w.SunExposure }},
+	{"wind_protection", func(w Weights) float64 { return w.WindProtection }},
+	{"low_tourism", func(w Weights) float64 { return w.LowTourism }},
+	{"family_friendliness", func(w Weights) float64 { return w.FamilyFriendliness }},
+	{"expat_community", func(w Weights) float64 { return w.ExpatCommunity }},
+	{"investment_focus", func(w Weights) float64 { return w.InvestmentFocus }},
+	{"walkability", func(w Weights) float64 { return w.Walkability }},
+	{"green_areas", func(w Weights) float64 { return w.GreenAreas }},
+	{"sea_proximity", func(w Weights) float64 { return w.SeaProximity }},
+	{"room_fit", func(w Weights) float64 { return w.RoomFit }},
+}
+
+var priorityFields = []struct {
+	key string
+	get func(domain.PreferenceWeights) float64
+}{
+	{"quietness", func(p domain.PreferenceWeights) float64 { return p.Quietness }},
+	{"sun_exposure", func(p domain.PreferenceWeights) float64 { return p.SunExposure }},
+	{"wind_protection", func(p domain.PreferenceWeights) float64 { return p.WindProtection }},
+	{"low_tourism", func(p domain.PreferenceWeights) float64 { return p.LowTourism }},
+	{"family_friendliness", func(p domain.PreferenceWeights) float64 { return p.FamilyFriendliness }},
+	{"expat_community", func(p domain.PreferenceWeights) float64 { return p.ExpatCommunity }},
+	{"investment_focus", func(p domain.PreferenceWeights) float64 { return p.InvestmentFocus }},
+	{"walkability", func(p domain.PreferenceWeights) float64 { return p.Walkability }},
+	{"green_areas", func(p domain.PreferenceWeights) float64 { return p.GreenAreas }},
+	{"sea_proximity", func(p domain.PreferenceWeights) float64 { return p.SeaProximity }},
+}
+
+// weightsByKey maps w's JSON keys to its numeric values, so factors can look
+// up their weight by key.
+func weightsByKey(w Weights) map[string]float64 {
+	out := make(map[string]float64, len(weightFields))
+	for _, f := range weightFields {
+		out[f.key] = f.get(w)
+	}
+	return out
+}
+
+// prioritiesByKey is weightsByKey for a client's priorities.
+func prioritiesByKey(p domain.PreferenceWeights) map[string]float64 {
+	out := make(map[string]float64, len(priorityFields))
+	for _, f := range priorityFields {
+		out[f.key] = f.get(p)
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package matching
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestRegistry_DuplicateKey(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	f := FeatureFactor("quietness", "quietness", true, func(f domain.Features) float64 { return f.Quietness })
+	if err := r.Register(f); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := r.Register(f); err == nil {
+		t.Fatalf("duplicate key registered without error")
+	}
+}
+
+func TestEngine_CustomRegistry(t *testing.T) {
+	t.Parallel()
+
+	// Only walkability is registered: other priorities are ignored.
+	r := NewRegistry()
+	r.MustRegister(FeatureFactor("walkability", "walkability", true, func(f domain.Features) float64 { return f.Walkability }))
+	e := NewEngineWithRegistry(DefaultWeights(), r)
+
+	profile := domain.ClientProfile{
+		Priorities: domain.PreferenceWeights{Walkability: 1, Quietness: 1},
+	}
+	props := []domain.Property{
+		{ID: "quiet", Features: domain.Features{Quietness: 1, Walkability: 0.2}},
+		{ID: "walk", Features: domain.Features{Quietness: 0, Walkability: 0.9}},
+	}
+
+	res := e.ScoreProperties(profile, props, 2)
+	if res[0].Property.ID != "walk" || res[0].Score != 90 {
+		t.Fatalf("results=%+v", res)
+	}
+	if len(res[0].Reasons) != 1 || res[0].Reasons[0].Type != "walkability" {
+		t.Fatalf("reasons=%+v", res[0].Reasons)
+	}
+}
+
+func TestFieldTables_CoverNumericFields(t *testing.T) {
+	t.Parallel()
+
+	// every numeric JSON field is in its table and read from the right field
+	check := func(v reflect.Value, byKey func() map[string]float64) {
+		t.Helper()
+		want := make(map[string]float64)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			key, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if f.Type.Kind() == reflect.Float64 && key != "" {
+				v.Field(i).SetFloat(float64(i + 1))
+				want[key] = float64(i + 1)
+			}
+		}
+		if got := byKey(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v want %v", v.Type(), got, want)
+		}
+	}
+	var w Weights
+	check(reflect.ValueOf(&w).Elem(), func() map[string]float64 { return weightsByKey(w) })
+	var p domain.PreferenceWeights
+	check(reflect.ValueOf(&p).Elem(), func() map[string]float64 { return prioritiesByKey(p) })
+
+	// min_features knows every feature key, in any case
+	for _, key := range domain.FeatureKeys() {
+		profile := domain.ClientProfile{HardFilters: domain.HardFilters{MinFeatures: map[string]float64{strings.ToUpper(key): 0}}}
+		if !passesHardFilters(profile, domain.Property{}) {
+			t.Fatalf("min_features %q rejected", key)
+		}
+	}
+}
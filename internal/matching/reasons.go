@@ -0,0 +1,256 @@
+package matching
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// Supported reason languages; DefaultLang is used when nothing matches.
+const (
+	LangEnglish = "en"
+	LangRussian = "ru"
+	LangSpanish = "es"
+	DefaultLang = LangEnglish
+)
+
+// IsSupportedLang reports whether reasons can be rendered in lang.
+func IsSupportedLang(lang string) bool {
+	_, ok := reasonTemplates[lang]
+	return ok
+}
+
+// reasonData is what reason templates can quote.
+type reasonData struct {
+	Label  string  // localized factor label
+	Level  string  // localized strong/good/mixed/weak
+	Value  float64 // 0..1 after transform
+	Raw    float64 // untransformed value, see HasRaw
+	HasRaw bool
+
+	Have, Want, Diff int // rooms
+
+	Price, BudgetMax float64
+	PctBelow         int // how far the price is below budget_max, %
+
+	Location, Preferred string
+	Matched             bool
+}
+
+// reasonFiller lets a factor add its own values to the template data.
+type reasonFiller interface {
+	fillReason(d *reasonData, profile domain.ClientProfile, p domain.Property)
+}
+
+var reasonLabels = map[string]map[string]string{
+	LangEnglish: {
+		"quietness":           "quietness",
+		"sun_exposure":        "sun exposure",
+		"wind_protection":     "wind protection",
+		"low_tourism":         "low tourism",
+		"family_friendliness": "family friendly",
+		"expat_community":     "expat friendly",
+		"investment_focus":    "investment potential",
+		"walkability":         "walkability",
+		"green_areas":         "green areas",
+		"sea_proximity":       "sea proximity",
+	},
+	LangRussian: {
+		"quietness":           "тишина",
+		"sun_exposure":        "солнце",
+		"wind_protection":     "защита от ветра",
+		"low_tourism":         "мало туристов",
+		"family_friendliness": "для семей",
+		"expat_community":     "сообщество экспатов",
+		"investment_focus":    "инвестиционный потенциал",
+		"walkability":         "пешая доступность",
+		"green_areas":         "зелёные зоны",
+		"sea_proximity":       "близость к морю",
+	},
+	LangSpanish: {
+		"quietness":           "tranquilidad",
+		"sun_exposure":        "sol",
+		"wind_protection":     "protección del viento",
+		"low_tourism":         "poco turismo",
+		"family_friendliness": "apto para familias",
+		"expat_community":     "comunidad extranjera",
+		"investment_focus":    "potencial de inversión",
+		"walkability":         "se puede ir a pie",
+		"green_areas":         "zonas verdes",
+		"sea_proximity":       "cercanía al mar",
+	},
+}
+
+// reasonLevels are indexed strong, good, mixed, weak.
+var reasonLevels = map[string][4]string{
+	LangEnglish: {"strong match", "good", "mixed", "weak"},
+	LangRussian: {"отлично", "хорошо", "средне", "слабо"},
+	LangSpanish: {"excelente", "bien", "regular", "débil"},
+}
+
+// reasonSources holds templates per language and factor key; "default"
+// covers factors without their own template.
+var reasonSources = map[string]map[string]string{
+	LangEnglish: {
+		"default":          `{{.Label}}: {{.Level}}{{if .HasRaw}} ({{ten .Raw}}/10){{end}}`,
+		"low_tourism":      `{{.Label}}: {{.Level}} (tourism intensity {{ten .Raw}}/10)`,
+		"sea_proximity":    `{{num .Raw}} km to the sea`,
+		"bedrooms":         `{{.Have}} {{plural .Have "bedroom" "bedrooms"}}{{template "rooms" .}}`,
+		"bathrooms":        `{{.Have}} {{plural .Have "bathroom" "bathrooms"}}{{template "rooms" .}}`,
+		"rooms":            `{{if eq .Have .Want}} as requested{{else if lt .Have .Want}}, {{.Diff}} fewer than requested{{else}}, {{.Diff}} more than requested{{end}}`,
+		"budget_closeness": `{{if gt .PctBelow 0}}priced {{.PctBelow}}% below your maximum budget{{else}}priced at your maximum budget{{end}}`,
+		"location_match":   `{{if .Matched}}in {{.Location}}, as preferred{{else}}{{.Location}} is outside your preferred area ({{.Preferred}}){{end}}`,
+	},
+	LangRussian: {
+		"default":          `{{.Label}}: {{.Level}}{{if .HasRaw}} ({{ten .Raw}}/10){{end}}`,
+		"low_tourism":      `{{.Label}}: {{.Level}} (туристическая нагрузка {{ten .Raw}}/10)`,
+		"sea_proximity":    `{{num .Raw}} км до моря`,
+		"bedrooms":         `{{.Have}} {{plural .Have "спальня" "спальни" "спален"}}{{template "rooms" .}}`,
+		"bathrooms":        `{{.Have}} {{plural .Have "санузел" "санузла" "санузлов"}}{{template "rooms" .}}`,
+		"rooms":            `{{if eq .Have .Want}}, как вы хотели{{else if lt .Have .Want}}, на {{.Diff}} меньше, чем нужно{{else}}, на {{.Diff}} больше, чем нужно{{end}}`,
+		"budget_closeness": `{{if gt .PctBelow 0}}цена на {{.PctBelow}}% ниже вашего максимального бюджета{{else}}цена на уровне вашего максимального бюджета{{end}}`,
+		"location_match":   `{{if .Matched}}{{.Location}}, как вы хотели{{else}}{{.Location}} — вне предпочтительного района ({{.Preferred}}){{end}}`,
+	},
+	LangSpanish: {
+		"default":          `{{.Label}}: {{.Level}}{{if .HasRaw}} ({{ten .Raw}}/10){{end}}`,
+		"low_tourism":      `{{.Label}}: {{.Level}} (intensidad turística {{ten .Raw}}/10)`,
+		"sea_proximity":    `a {{num .Raw}} km del mar`,
+		"bedrooms":         `{{.Have}} {{plural .Have "dormitorio" "dormitorios"}}{{template "rooms" .}}`,
+		"bathrooms":        `{{.Have}} {{plural .Have "baño" "baños"}}{{template "rooms" .}}`,
+		"rooms":            `{{if eq .Have .Want}}, como pidió{{else if lt .Have .Want}}, {{.Diff}} menos de lo pedido{{else}}, {{.Diff}} más de lo pedido{{end}}`,
+		"budget_closeness": `{{if gt .PctBelow 0}}precio un {{.PctBelow}}% por debajo de su presupuesto máximo{{else}}precio en su presupuesto máximo{{end}}`,
+		"location_match":   `{{if .Matched}}en {{.Location}}, como prefiere{{else}}{{.Location}} está fuera de la zona preferida ({{.Preferred}}){{end}}`,
+	},
+}
+
+// reasonTemplates is reasonSources parsed at init: lang -> key -> template.
+var reasonTemplates = func() map[string]map[string]*template.Template {
+	out := make(map[string]map[string]*template.Template, len(reasonSources))
+	for lang, srcs := range reasonSources {
+		funcs := template.FuncMap{
+			"num":    fmtNum,
+			"ten":    func(v float64) string { return fmtNum(math.Round(v*100) / 10) },
+			"plural": pluralFunc(lang),
+		}
+		// one set per language so shared sub-templates ("rooms") resolve
+		root := template.New(lang).Funcs(funcs)
+		for key, src := range srcs {
+			template.Must(root.New(key).Parse(src))
+		}
+		out[lang] = make(map[string]*template.Template, len(srcs))
+		for key := range srcs {
+			out[lang][key] = root.Lookup(key)
+		}
+	}
+	return out
+}()
+
+// pluralFunc picks a noun form: en/es take (one, many), ru takes (one, few, many).
+func pluralFunc(lang string) func(n int, forms ...string) string {
+	return func(n int, forms ...string) string {
+		if len(forms) == 0 {
+			return ""
+		}
+		if n < 0 {
+			n = -n
+		}
+		idx := 0
+		if lang == LangRussian && len(forms) == 3 {
+			switch {
+			case n%10 == 1 && n%100 != 11:
+				idx = 0
+			case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+				idx = 1
+			default:
+				idx = 2
+			}
+		} else if n != 1 {
+			idx = 1
+		}
+		if idx >= len(forms) {
+			idx = len(forms) - 1
+		}
+		return forms[idx]
+	}
+}
+
+// renderReason builds the localized message for one factor.
+func renderReason(lang string, f Factor, profile domain.ClientProfile, p domain.Property, v float64, raw *float64) string {
+	tpls, ok := reasonTemplates[lang]
+	if !ok {
+		lang, tpls = DefaultLang, reasonTemplates[DefaultLang]
+	}
+
+	d := reasonData{Label: f.Label(), Level: reasonLevel(lang, v), Value: v}
+	if l, ok := reasonLabels[lang][f.Key()]; ok {
+		d.Label = l
+	}
+	if raw != nil {
+		d.Raw, d.HasRaw = *raw, true
+	}
+	if rf, ok := f.(reasonFiller); ok {
+		rf.fillReason(&d, profile, p)
+	}
+
+	t, ok := tpls[f.Key()]
+	if !ok {
+		t = tpls["default"]
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, d); err != nil {
+		return d.Label + ": " + d.Level
+	}
+	return b.String()
+}
+
+func reasonLevel(lang string, v float64) string {
+	levels := reasonLevels[lang]
+	switch {
+	case v >= 0.8:
+		return levels[0]
+	case v >= 0.6:
+		return levels[1]
+	case v >= 0.4:
+		return levels[2]
+	default:
+		return levels[3]
+	}
+}
+
+// NegotiateLang picks a supported language from an Accept-Language header
+// ("ru-RU,ru;q=0.9,en;q=0.8"), or DefaultLang.
+func NegotiateLang(header string) string {
+	type cand struct {
+		lang string
+		q    float64
+	}
+	var cands []cand
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(f), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		primary, _, _ := strings.Cut(tag, "-")
+		cands = append(cands, cand{primary, q})
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].q > cands[j].q })
+	for _, c := range cands {
+		if c.q > 0 && IsSupportedLang(c.lang) {
+			return c.lang
+		}
+	}
+	return DefaultLang
+}
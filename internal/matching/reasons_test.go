@@ -0,0 +1,84 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestReasons_Localized(t *testing.T) {
+	t.Parallel()
+
+	profile := domain.ClientProfile{
+		BudgetMax:          400000,
+		LocationPreference: "Valencia",
+		DesiredBedrooms:    3,
+		Priorities:         domain.PreferenceWeights{SeaProximity: 1, Quietness: 1},
+	}
+	p := domain.Property{
+		ID: "es-001", Location: "Valencia", Price: 320000, Bedrooms: 3,
+		Features: domain.Features{DistanceToSeaKm: 1.5, Quietness: 0.6},
+	}
+
+	want := map[string][]string{
+		LangEnglish: {"1.5 km to the sea", "priced 20% below your maximum budget", "3 bedrooms as requested", "quietness: good (6/10)", "in Valencia, as preferred"},
+		LangRussian: {"1.5 км до моря", "цена на 20% ниже вашего максимального бюджета", "3 спальни, как вы хотели", "тишина: хорошо (6/10)"},
+		LangSpanish: {"a 1.5 km del mar", "precio un 20% por debajo de su presupuesto máximo", "3 dormitorios, como pidió"},
+	}
+	for lang, msgs := range want {
+		res := NewEngine(DefaultWeights()).WithLang(lang).ScoreProperties(profile, []domain.Property{p}, 1)
+		got := map[string]bool{}
+		for _, r := range res[0].Reasons {
+			got[r.Message] = true
+		}
+		for _, m := range msgs {
+			if !got[m] {
+				t.Fatalf("%s: missing %q in %+v", lang, m, res[0].Reasons)
+			}
+		}
+	}
+}
+
+func TestReasons_RoomsMismatchAndPlural(t *testing.T) {
+	t.Parallel()
+
+	f := DefaultRegistry(DefaultWeights())
+	bedrooms, _ := f.Get("bedrooms")
+	cases := []struct {
+		lang       string
+		have, want int
+		msg        string
+	}{
+		{LangEnglish, 1, 2, "1 bedroom, 1 fewer than requested"},
+		{LangEnglish, 4, 2, "4 bedrooms, 2 more than requested"},
+		{LangRussian, 1, 1, "1 спальня, как вы хотели"},
+		{LangRussian, 5, 3, "5 спален, на 2 больше, чем нужно"},
+		{LangSpanish, 1, 2, "1 dormitorio, 1 menos de lo pedido"},
+		{"de", 2, 2, "2 bedrooms as requested"},
+	}
+	for _, c := range cases {
+		profile := domain.ClientProfile{DesiredBedrooms: c.want}
+		p := domain.Property{Bedrooms: c.have}
+		if got := renderReason(c.lang, bedrooms, profile, p, 1, nil); got != c.msg {
+			t.Fatalf("%s %d/%d: %q want %q", c.lang, c.have, c.want, got, c.msg)
+		}
+	}
+}
+
+func TestNegotiateLang(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"":                        LangEnglish,
+		"ru-RU,ru;q=0.9,en;q=0.8": LangRussian,
+		"de-DE,es;q=0.7,en;q=0.5": LangSpanish,
+		"en;q=0.3, ru;q=0.8":      LangRussian,
+		"fr, de":                  LangEnglish,
+		"es;q=0, ru;q=0.1":        LangRussian,
+	}
+	for header, want := range cases {
+		if got := NegotiateLang(header); got != want {
+			t.Fatalf("NegotiateLang(%q)=%q want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package matching
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// maxRelaxations caps how many suggestions SuggestRelaxations returns.
+const maxRelaxations = 10
+
+var (
+	budgetSteps = []float64{0.05, 0.10, 0.15, 0.20, 0.30}
+	areaSteps   = []float64{0.10, 0.20, 0.30}
+	seaSteps    = []float64{0.5, 1.0, 2.0}
+)
+
+// candidate is a relaxed copy of the profile with a human-readable description.
+type candidate struct {
+	constraint string
+	message    string
+	profile    domain.ClientProfile
+}
+
+// SuggestRelaxations looks for minimal changes to the profile's hard constraints
+// that would let more properties through when fewer than limit pass today.
+// Every constraint yields at most one suggestion: the smallest step that fills
+// the list, or failing that the step that unlocks the most properties.
+// Suggestions that fill the list come first, then by unlocked count.
+func (e *Engine) SuggestRelaxations(profile domain.ClientProfile, properties []domain.Property, limit int) []domain.Relaxation {
+	return e.SuggestRelaxationsSeq(profile, slices.Values(properties), limit)
+}
+
+// SuggestRelaxationsSeq is SuggestRelaxations over a stream of properties.
+// It reads the stream once and keeps only per-candidate counters.
+func (e *Engine) SuggestRelaxationsSeq(profile domain.ClientProfile, properties iter.Seq[domain.Property], limit int) []domain.Relaxation {
+	if limit <= 0 {
+		limit = 5
+	}
+	groups := relaxationGroups(profile)
+	unlocked := make([][]int, len(groups))
+	for g := range groups {
+		unlocked[g] = make([]int, len(groups[g]))
+	}
+
+	base := 0
+	for p := range properties {
+		// Relaxing never excludes anything, so a passing property counts as is.
+		if passesHardFilters(profile, p) {
+			base++
+			continue
+		}
+		for g, group := range groups {
+			for i := range group {
+				if passesHardFilters(group[i].profile, p) {
+					unlocked[g][i]++
+				}
+			}
+		}
+	}
+	need := limit - base
+	if need <= 0 {
+		return nil
+	}
+
+	var out []domain.Relaxation
+	for g, group := range groups {
+		var best *candidate
+		bestUnlocked := 0
+		for i, n := range unlocked[g] {
+			if n > bestUnlocked {
+				best, bestUnlocked = &group[i], n
+			}
+			if n >= need {
+				break
+			}
+		}
+		if best == nil {
+			continue
+		}
+		out = append(out, domain.Relaxation{
+			Constraint: best.constraint,
+			Message:    best.message,
+			Unlocked:   bestUnlocked,
+			FillsLimit: bestUnlocked >= need,
+			Profile:    best.profile,
+		})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].FillsLimit != out[j].FillsLimit {
+			return out[i].FillsLimit
+		}
+		return out[i].Unlocked > out[j].Unlocked
+	})
+	if len(out) > maxRelaxations {
+		out = out[:maxRelaxations]
+	}
+	return out
+}
+
+// relaxationGroups lists candidate relaxations per constraint, smallest change first.
+func relaxationGroups(profile domain.ClientProfile) [][]candidate {
+	hf := profile.HardFilters
+	var groups [][]candidate
+	add := func(g []candidate) {
+		if len(g) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	relaxed := func(mutate func(p *domain.ClientProfile)) domain.ClientProfile {
+		p := profile
+		p.HardFilters = copyHardFilters(hf)
+		mutate(&p)
+		return p
+	}
+
+	if profile.BudgetMax > 0 {
+		var g []candidate
+		for _, step := range budgetSteps {
+			v := math.Round(profile.BudgetMax * (1 + step))
+			g = append(g, candidate{
+				constraint: "budget_max",
+				message:    fmt.Sprintf("raise budget_max by %d%% to %s", pct(step), fmtNum(v)),
+				profile:    relaxed(func(p *domain.ClientProfile) { p.BudgetMax = v }),
+			})
+		}
+		add(g)
+	}
+	if profile.BudgetMin > 0 {
+		var g []candidate
+		for _, step := range budgetSteps {
+			v := math.Round(profile.BudgetMin * (1 - step))
+			g = append(g, candidate{
+				constraint: "budget_min",
+				message:    fmt.Sprintf("lower budget_min by %d%% to %s", pct(step), fmtNum(v)),
+				profile:    relaxed(func(p *domain.ClientProfile) { p.BudgetMin = v }),
+			})
+		}
+		add(g)
+	}
+
+	for i, a := range hf.MustHaveAmenities {
+		add([]candidate{{
+			constraint: "must_have_amenities",
+			message:    "drop must-have amenity: " + a,
+			profile: relaxed(func(p *domain.ClientProfile) {
+				p.HardFilters.MustHaveAmenities = without(p.HardFilters.MustHaveAmenities, i)
+			}),
+		}})
+	}
+	for i, a := range hf.ExcludedAmenities {
+		add([]candidate{{
+			constraint: "excluded_amenities",
+			message:    "allow excluded amenity: " + a,
+			profile: relaxed(func(p *domain.ClientProfile) {
+				p.HardFilters.ExcludedAmenities = without(p.HardFilters.ExcludedAmenities, i)
+			}),
+		}})
+	}
+
+	if len(normalizeList(hf.AllowedLocations)) > 0 {
+		add([]candidate{{
+			constraint: "allowed_locations",
+			message:    "widen location: drop allowed_locations",
+			profile:    relaxed(func(p *domain.ClientProfile) { p.HardFilters.AllowedLocations = nil }),
+		}})
+	}
+	for i, l := range hf.BlockedLocations {
+		add([]candidate{{
+			constraint: "blocked_locations",
+			message:    "unblock location: " + l,
+			profile: relaxed(func(p *domain.ClientProfile) {
+				p.HardFilters.BlockedLocations = without(p.HardFilters.BlockedLocations, i)
+			}),
+		}})
+	}
+
+	add(lowerIntSteps("min_bedrooms", hf.MinBedrooms, func(p *domain.ClientProfile, v int) { p.HardFilters.MinBedrooms = v }, relaxed))
+	add(lowerIntSteps("min_bathrooms", hf.MinBathrooms, func(p *domain.ClientProfile, v int) { p.HardFilters.MinBathrooms = v }, relaxed))
+	add(raiseIntStep("max_bedrooms", hf.MaxBedrooms, func(p *domain.ClientProfile, v int) { p.HardFilters.MaxBedrooms = v }, relaxed))
+	add(raiseIntStep("max_bathrooms", hf.MaxBathrooms, func(p *domain.ClientProfile, v int) { p.HardFilters.MaxBathrooms = v }, relaxed))
+
+	if hf.MinAreaSQM > 0 {
+		var g []candidate
+		for _, step := range areaSteps {
+			v := roundTo(hf.MinAreaSQM*(1-step), 1)
+			g = append(g, candidate{
+				constraint: "min_area_sqm",
+				message:    fmt.Sprintf("lower min_area_sqm by %d%% to %s", pct(step), fmtNum(v)),
+				profile:    relaxed(func(p *domain.ClientProfile) { p.HardFilters.MinAreaSQM = v }),
+			})
+		}
+		add(g)
+	}
+	if hf.MaxAreaSQM > 0 {
+		var g []candidate
+		for _, step := range areaSteps {
+			v := roundTo(hf.MaxAreaSQM*(1+step), 1)
+			g = append(g, candidate{
+				constraint: "max_area_sqm",
+				message:    fmt.Sprintf("raise max_area_sqm by %d%% to %s", pct(step), fmtNum(v)),
+				profile:    relaxed(func(p *domain.ClientProfile) { p.HardFilters.MaxAreaSQM = v }),
+			})
+		}
+		add(g)
+	}
+	if hf.MaxDistanceToSeaKm > 0 {
+		var g []candidate
+		for _, step := range seaSteps {
+			v := roundTo(hf.MaxDistanceToSeaKm*(1+step), 2)
+			g = append(g, candidate{
+				constraint: "max_distance_to_sea_km",
+				message:    fmt.Sprintf("raise max_distance_to_sea_km to %s", fmtNum(v)),
+				profile:    relaxed(func(p *domain.ClientProfile) { p.HardFilters.MaxDistanceToSeaKm = v }),
+			})
+		}
+		add(g)
+	}
+
+	keys := make([]string, 0, len(hf.MinFeatures))
+	for k := range hf.MinFeatures {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add([]candidate{{
+			constraint: "min_features",
+			message:    "drop min feature: " + k,
+			profile:    relaxed(func(p *domain.ClientProfile) { delete(p.HardFilters.MinFeatures, k) }),
+		}})
+	}
+
+	return groups
+}
+
+// maxIntSteps caps the one-by-one steps of lowerIntSteps, so an absurd
+// minimum costs a few candidates rather than one per integer.
+const maxIntSteps = 3
+
+// lowerIntSteps lowers a minimum one by one, at most maxIntSteps times, then
+// drops it (0 = not set).
+func lowerIntSteps(name string, cur int, set func(*domain.ClientProfile, int), relaxed func(func(*domain.ClientProfile)) domain.ClientProfile) []candidate {
+	if cur <= 0 {
+		return nil
+	}
+	var g []candidate
+	for v := cur - 1; v > 0 && v >= cur-maxIntSteps; v-- {
+		g = append(g, candidate{
+			constraint: name,
+			message:    fmt.Sprintf("lower %s to %d", name, v),
+			profile:    relaxed(func(p *domain.ClientProfile) { set(p, v) }),
+		})
+	}
+	return append(g, candidate{
+		constraint: name,
+		message:    "drop " + name,
+		profile:    relaxed(func(p *domain.ClientProfile) { set(p, 0) }),
+	})
+}
+
+// raiseIntStep raises a maximum by one, then drops it.
+func raiseIntStep(name string, cur int, set func(*domain.ClientProfile, int), relaxed func(func(*domain.ClientProfile)) domain.ClientProfile) []candidate {
+	if cur <= 0 {
+		return nil
+	}
+	return []candidate{
+		{
+			constraint: name,
+			message:    fmt.Sprintf("raise %s to %d", name, cur+1),
+			profile:    relaxed(func(p *domain.ClientProfile) { set(p, cur+1) }),
+		},
+		{
+			constraint: name,
+			message:    "drop " + name,
+			profile:    relaxed(func(p *domain.ClientProfile) { set(p, 0) }),
+		},
+	}
+}
+
+func copyHardFilters(hf domain.HardFilters) domain.HardFilters {
+	out := hf
+	out.MustHaveAmenities = append([]string(nil), hf.MustHaveAmenities...)
+	out.ExcludedAmenities = append([]string(nil), hf.ExcludedAmenities...)
+	out.AllowedLocations = append([]string(nil), hf.AllowedLocations...)
+	out.BlockedLocations = append([]string(nil), hf.BlockedLocations...)
+	if hf.MinFeatures != nil {
+		out.MinFeatures = make(map[string]float64, len(hf.MinFeatures))
+		for k, v := range hf.MinFeatures {
+			out.MinFeatures[k] = v
+		}
+	}
+	return out
+}
+
+func without(items []string, i int) []string {
+	out := make([]string, 0, len(items)-1)
+	out = append(out, items[:i]...)
+	return append(out, items[i+1:]...)
+}
+
+func pct(step float64) int {
+	return int(math.Round(step * 100))
+}
+
+func roundTo(v float64, digits int) float64 {
+	m := math.Pow(10, float64(digits))
+	return math.Round(v*m) / m
+}
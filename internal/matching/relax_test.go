@@ -0,0 +1,71 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestSuggestRelaxations(t *testing.T) {
+	t.Parallel()
+
+	e := NewEngine(DefaultWeights())
+	profile := domain.ClientProfile{
+		BudgetMax:   400000,
+		HardFilters: domain.HardFilters{MustHaveAmenities: []string{"parking", "pool"}},
+	}
+	props := []domain.Property{
+		{ID: "a", Price: 350000, Amenities: []string{"parking", "pool"}},
+		{ID: "b", Price: 380000, Amenities: []string{"parking"}},
+		{ID: "c", Price: 390000, Amenities: []string{"parking"}},
+		{ID: "d", Price: 430000, Amenities: []string{"parking", "pool"}},
+	}
+
+	if got := e.SuggestRelaxations(profile, props, 1); got != nil {
+		t.Fatalf("limit already filled, got %+v", got)
+	}
+
+	got := e.SuggestRelaxations(profile, props, 3)
+	if len(got) != 2 {
+		t.Fatalf("suggestions=%+v", got)
+	}
+
+	// dropping "pool" unlocks b and c and fills the list; budget only unlocks d.
+	if got[0].Constraint != "must_have_amenities" || got[0].Unlocked != 2 || !got[0].FillsLimit {
+		t.Fatalf("first=%+v", got[0])
+	}
+	if got[0].Message != "drop must-have amenity: pool" {
+		t.Fatalf("message=%q", got[0].Message)
+	}
+	if got[1].Constraint != "budget_max" || got[1].Unlocked != 1 || got[1].FillsLimit {
+		t.Fatalf("second=%+v", got[1])
+	}
+	// smallest step reaching 430000 is +10%.
+	if got[1].Message != "raise budget_max by 10% to 440000" || got[1].Profile.BudgetMax != 440000 {
+		t.Fatalf("budget=%+v", got[1])
+	}
+
+	// the original profile must stay untouched.
+	if len(profile.HardFilters.MustHaveAmenities) != 2 || profile.BudgetMax != 400000 {
+		t.Fatalf("profile mutated: %+v", profile)
+	}
+}
+
+func TestSuggestRelaxations_CapsIntSteps(t *testing.T) {
+	t.Parallel()
+
+	profile := domain.ClientProfile{HardFilters: domain.HardFilters{MinBedrooms: 1000000}}
+	groups := relaxationGroups(profile)
+	if len(groups) != 1 || len(groups[0]) != maxIntSteps+1 {
+		t.Fatalf("groups=%d candidates=%d", len(groups), len(groups[0]))
+	}
+	if last := groups[0][maxIntSteps]; last.message != "drop min_bedrooms" || last.profile.HardFilters.MinBedrooms != 0 {
+		t.Fatalf("last=%+v", last)
+	}
+
+	// no step near a million bedrooms helps; only dropping the minimum does.
+	got := NewEngine(DefaultWeights()).SuggestRelaxations(profile, []domain.Property{{ID: "a", Bedrooms: 2}}, 1)
+	if len(got) != 1 || got[0].Message != "drop min_bedrooms" || !got[0].FillsLimit {
+		t.Fatalf("suggestions=%+v", got)
+	}
+}
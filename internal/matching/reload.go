@@ -0,0 +1,57 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// ReloadFromFile loads and validates weights from path and swaps them into
+// the engine. On a load error the current weights stay in place; an error
+// matching ErrPresetDropped comes after the swap, as from SetWeights.
+func (e *Engine) ReloadFromFile(path string) (WeightsVersion, error) {
+	w, err := LoadWeightsFromFile(path)
+	if err != nil {
+		return e.Version(), err
+	}
+	return e.SetWeights(w)
+}
+
+// WatchWeightsFile polls path every interval and reloads the weights when its
+// modification time or size changes. It blocks until ctx is done.
+func (e *Engine) WatchWeightsFile(ctx context.Context, path string, interval time.Duration, logf func(format string, args ...any)) {
+	if interval <= 0 {
+		return
+	}
+	last, _ := os.Stat(path)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+			continue
+		}
+		last = fi
+
+		v, err := e.ReloadFromFile(path)
+		if err != nil && !errors.Is(err, ErrPresetDropped) {
+			logf("weights reload from %s rejected: %v", path, err)
+			continue
+		}
+		logf("weights reloaded from %s (version %d)", path, v.Version)
+		if err != nil {
+			logf("weights reload from %s: %v", path, err)
+		}
+	}
+}
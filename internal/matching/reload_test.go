@@ -0,0 +1,140 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestEngine_SetWeightsConcurrentWithScoring(t *testing.T) {
+	t.Parallel()
+
+	e := NewEngine(DefaultWeights())
+	profile := domain.ClientProfile{Priorities: domain.PreferenceWeights{Quietness: 1, Walkability: 1}}
+	props := []domain.Property{{ID: "a", Features: domain.Features{Quietness: 1, Walkability: 0}}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				// Either old (1:0.7) or new (1:1) weights, never a mix of both.
+				s := e.ScoreProperties(profile, props, 1)[0].Score
+				if s != 58.8 && s != 50 {
+					t.Errorf("unexpected score %v", s)
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		w := DefaultWeights()
+		if j%2 == 0 {
+			w.Walkability = 1
+		}
+		e.SetWeights(w)
+	}
+	wg.Wait()
+
+	if v := e.Version().Version; v != 101 {
+		t.Fatalf("version=%d want=101", v)
+	}
+}
+
+func TestEngine_WatchWeightsFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "weights.json")
+	if err := os.WriteFile(path, []byte(`{"quietness": 0.5}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(DefaultWeights())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.WatchWeightsFile(ctx, path, 10*time.Millisecond, t.Logf)
+
+	// Invalid content is rejected and the current weights stay.
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"quietness": 0.3, "curves": {"x": {"type": "linear"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if e.Weights().Quietness != DefaultWeights().Quietness {
+		t.Fatalf("invalid file applied: quietness=%v", e.Weights().Quietness)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"quietness": 0.2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for e.Weights().Quietness != 0.2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("weights not reloaded, quietness=%v", e.Weights().Quietness)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if e.Version().Version < 2 {
+		t.Fatalf("version=%d want >= 2", e.Version().Version)
+	}
+}
+
+func TestEngine_SetWeightsRelayersPresets(t *testing.T) {
+	t.Parallel()
+
+	e := NewEngine(DefaultWeights())
+	e.SetPresets(map[string][]byte{"investor": []byte(`{"investment_focus": 2}`)})
+	pinned := e.Snapshot()
+
+	w := DefaultWeights()
+	w.Quietness = 0.1
+	e.SetWeights(w)
+
+	// the preset follows the reloaded base in the same swap
+	got, ok := e.Preset("investor")
+	if !ok || got.InvestmentFocus != 2 || got.Quietness != 0.1 {
+		t.Fatalf("preset after reload: ok=%v %+v", ok, got)
+	}
+	// a request pinned before the reload keeps the old base
+	if old, _ := pinned.Preset("investor"); old.Quietness != DefaultWeights().Quietness {
+		t.Fatalf("pinned preset quietness=%v", old.Quietness)
+	}
+	if _, ok := e.Preset("nope"); ok {
+		t.Fatalf("unknown preset found")
+	}
+}
+
+func TestEngine_ReportsDroppedPresets(t *testing.T) {
+	t.Parallel()
+
+	e := NewEngine(DefaultWeights())
+	err := e.SetPresets(map[string][]byte{
+		"investor": []byte(`{"investment_focus": 2}`),
+		"broken":   []byte(`{"curves": {"view": {"type": "linear"}}}`),
+	})
+	if !errors.Is(err, ErrPresetDropped) || !strings.Contains(err.Error(), `preset "broken"`) {
+		t.Fatalf("SetPresets err=%v", err)
+	}
+	if _, ok := e.Preset("broken"); ok {
+		t.Fatalf("broken preset kept")
+	}
+
+	// the weights are swapped in anyway, the good preset follows them
+	w := DefaultWeights()
+	w.Quietness = 0.1
+	v, err := e.SetWeights(w)
+	if !errors.Is(err, ErrPresetDropped) || v.Version != 2 || e.Weights().Quietness != 0.1 {
+		t.Fatalf("SetWeights v=%+v err=%v", v, err)
+	}
+	if got, ok := e.Preset("investor"); !ok || got.Quietness != 0.1 {
+		t.Fatalf("investor preset: ok=%v %+v", ok, got)
+	}
+}
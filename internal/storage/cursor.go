@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// Page selects part of an ordered listing: the rows after Cursor when it is
+// set, otherwise Offset rows skipped.
+type Page struct {
+	Sort   []SortKey
+	Cursor *Cursor
+	Limit  int
+	Offset int
+}
+
+// Cursor is a keyset position: the sort values and id of the last row seen.
+// Rows added or deleted before it do not shift the following pages.
+type Cursor struct {
+	Order  string `json:"o"` // the order it was made for, see cursorOrder
+	Values []any  `json:"v"`
+	ID     string `json:"id"`
+}
+
+var errBadCursor = errors.New("invalid cursor")
+
+// insertionOrder marks a cursor into the insertion order of a MemoryStore
+// listing without sort or text query; its single value is the sequence
+// number of the last property.
+const insertionOrder = "insertion"
+
+// DecodeCursor parses a token made by Cursor.Encode.
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return nil, errBadCursor
+	}
+	return &c, nil
+}
+
+// Encode returns the opaque token handed to clients.
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Valid reports whether c was made for this sort (with the same text query
+// or distance origin in f) and holds values of the right types.
+func (c *Cursor) Valid(keys []SortKey, f PropertyFilter) bool {
+	keys = effectiveSort(keys, f)
+	if c.Order == insertionOrder {
+		if len(keys) != 0 || len(c.Values) != 1 {
+			return false
+		}
+		_, ok := c.Values[0].(float64)
+		return ok
+	}
+	if c.Order != cursorOrder(keys, f) || len(c.Values) != len(keys) {
+		return false
+	}
+	for i, k := range keys {
+		f := sortFields[k.Field]
+		switch c.Values[i].(type) {
+		case nil:
+			if !f.nullable {
+				return false
+			}
+		case float64:
+			if f.text {
+				return false
+			}
+		case string:
+			if !f.text {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// cursorOrder identifies an order; relevance depends on the query terms and
+// distance on its origin.
+func cursorOrder(keys []SortKey, f PropertyFilter) string {
+	if len(keys) == 1 && keys[0].Field == relevanceField {
+		return relevanceField + ":" + strings.Join(f.Text, " ")
+	}
+	order := FormatSort(keys)
+	if f.Near != nil && slices.ContainsFunc(keys, func(k SortKey) bool { return k.Field == "distance" }) {
+		order += "@" + distanceSQL(f.Near)
+	}
+	return order
+}
+
+func cursorAt(p domain.Property, keys []SortKey, f PropertyFilter) *Cursor {
+	return &Cursor{Order: cursorOrder(keys, f), Values: sortTuple(p, keys, f), ID: p.ID}
+}
+
+// after reports whether p comes after c.
+func (c *Cursor) after(p domain.Property, keys []SortKey, f PropertyFilter) bool {
+	if n := compareTuples(sortTuple(p, keys, f), c.Values, keys); n != 0 {
+		return n > 0
+	}
+	return p.ID > c.ID
+}
+
+// PageProperties orders props, already filtered by f, like the SQL listing
+// (by pg.Sort, by relevance to f.Text without it, by id last) and cuts out
+// pg. next is the cursor of the page's last row when more rows follow.
+func PageProperties(props []domain.Property, f PropertyFilter, pg Page) (page []domain.Property, next *Cursor) {
+	keys := effectiveSort(pg.Sort, f)
+	sortProperties(props, keys, f)
+
+	start := min(max(pg.Offset, 0), len(props))
+	if pg.Cursor != nil {
+		start = len(props)
+		for i, p := range props {
+			if pg.Cursor.after(p, keys, f) {
+				start = i
+				break
+			}
+		}
+	}
+	end := min(start+pageLimit(pg.Limit), len(props))
+	page = props[start:end]
+	if end < len(props) && len(page) > 0 {
+		next = cursorAt(page[len(page)-1], keys, f)
+	}
+	return page, next
+}
+
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return 20
+	}
+	return limit
+}
+
+// afterClause is the SQL twin of Cursor.after: a row is after c if it beats
+// c on the first key that differs, NULL counting as worst, or ties on every
+// key and has a greater id.
+func (c *Cursor) afterClause(keys []SortKey, f PropertyFilter) (string, []any) {
+	var (
+		branches []string
+		args     []any
+		eq       []string // equality with c on the keys so far
+		eqArgs   []any
+	)
+	branch := func(cond string, condArgs ...any) {
+		branches = append(branches, "("+strings.Join(append(append([]string{}, eq...), cond), " AND ")+")")
+		args = append(append(args, eqArgs...), condArgs...)
+	}
+	for i, k := range keys {
+		field := sortFields[k.Field]
+		expr := field.sql(f)
+		v := c.Values[i]
+		if v == nil {
+			// only other NULLs can follow a NULL, and they tie on this key
+			eq = append(eq, expr+" IS NULL")
+			continue
+		}
+		op := ">"
+		if k.Desc {
+			op = "<"
+		}
+		cond := expr + " " + op + " ?"
+		if field.nullable {
+			cond = "(" + cond + " OR " + expr + " IS NULL)"
+		}
+		branch(cond, v)
+		eq = append(eq, expr+" = ?")
+		eqArgs = append(eqArgs, v)
+	}
+	branch("id > ?", c.ID)
+	return "(" + strings.Join(branches, " OR ") + ")", args
+}
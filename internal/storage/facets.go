@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// FacetRequest says which facets to count. PriceEdges split prices into
+// len+1 buckets: below the first edge, [edge i, edge i+1), and from the last edge.
+type FacetRequest struct {
+	Location, Bedrooms, Amenities, Price bool
+	PriceEdges                           []float64 // ascending
+}
+
+// Any reports whether any facet is requested.
+func (r FacetRequest) Any() bool {
+	return r.Location || r.Bedrooms || r.Amenities || r.Price
+}
+
+// Facets are counts over every property matching a filter, not just a page.
+// Value facets are ordered by count desc, then value; bedrooms by value.
+type Facets struct {
+	Location  []FacetCount  `json:"location,omitempty"`
+	Bedrooms  []FacetCount  `json:"bedrooms,omitempty"`
+	Amenities []FacetCount  `json:"amenities,omitempty"` // lowercased names, each property counted once
+	Price     []PriceBucket `json:"price,omitempty"`     // every bucket, empty ones too
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket counts prices in [Min, Max); a nil bound is open.
+type PriceBucket struct {
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// CountFacets computes facets over props, which must already be filtered.
+func CountFacets(props []domain.Property, req FacetRequest) Facets {
+	location := make(map[string]int)
+	bedrooms := make(map[string]int)
+	amenities := make(map[string]int)
+	prices := make([]int, len(req.PriceEdges)+1)
+
+	for _, p := range props {
+		location[p.Location]++
+		bedrooms[strconv.Itoa(p.Bedrooms)]++
+		seen := make(map[string]bool, len(p.Amenities))
+		for _, a := range p.Amenities {
+			if k := amenityKey(a); k != "" && !seen[k] {
+				seen[k] = true
+				amenities[k]++
+			}
+		}
+		prices[priceBucket(p.Price, req.PriceEdges)]++
+	}
+
+	var out Facets
+	if req.Location {
+		out.Location = byCount(location)
+	}
+	if req.Bedrooms {
+		out.Bedrooms = byBedrooms(bedrooms)
+	}
+	if req.Amenities {
+		out.Amenities = byCount(amenities)
+	}
+	if req.Price {
+		out.Price = priceBuckets(req.PriceEdges, prices)
+	}
+	return out
+}
+
+// Facets is CountFacets done in SQL over the properties matching f.
+func (s *SQLiteStore) Facets(ctx context.Context, f PropertyFilter, req FacetRequest) (Facets, error) {
+	whereSQL, args := whereClause(f)
+	count := func(query string, args ...any) (map[string]int, error) {
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		out := make(map[string]int)
+		for rows.Next() {
+			var v string
+			var n int
+			if err := rows.Scan(&v, &n); err != nil {
+				return nil, err
+			}
+			out[v] = n
+		}
+		return out, rows.Err()
+	}
+
+	var out Facets
+	if req.Location {
+		m, err := count(`SELECT location, COUNT(*) FROM properties `+whereSQL+` GROUP BY location`, args...)
+		if err != nil {
+			return Facets{}, err
+		}
+		out.Location = byCount(m)
+	}
+	if req.Bedrooms {
+		m, err := count(`SELECT CAST(bedrooms AS TEXT), COUNT(*) FROM properties `+whereSQL+` GROUP BY bedrooms`, args...)
+		if err != nil {
+			return Facets{}, err
+		}
+		out.Bedrooms = byBedrooms(m)
+	}
+	if req.Amenities {
+		m, err := count(`
+SELECT a.name_key, COUNT(DISTINCT a.property_id)
+FROM property_amenities a
+WHERE a.name_key <> '' AND a.property_id IN (SELECT id FROM properties `+whereSQL+`)
+GROUP BY a.name_key`, args...)
+		if err != nil {
+			return Facets{}, err
+		}
+		out.Amenities = byCount(m)
+	}
+	if req.Price {
+		// CASE WHEN price < e1 THEN 0 WHEN price < e2 THEN 1 ... ELSE n END
+		var b strings.Builder
+		bucketArgs := make([]any, 0, len(req.PriceEdges)+len(args))
+		b.WriteString("CASE")
+		for i, e := range req.PriceEdges {
+			fmt.Fprintf(&b, " WHEN price < ? THEN %d", i)
+			bucketArgs = append(bucketArgs, e)
+		}
+		fmt.Fprintf(&b, " ELSE %d END", len(req.PriceEdges))
+
+		m, err := count(`SELECT CAST(`+b.String()+` AS TEXT) AS bucket, COUNT(*) FROM properties `+whereSQL+` GROUP BY bucket`,
+			append(bucketArgs, args...)...)
+		if err != nil {
+			return Facets{}, err
+		}
+		counts := make([]int, len(req.PriceEdges)+1)
+		for k, n := range m {
+			i, _ := strconv.Atoi(k)
+			counts[i] = n
+		}
+		out.Price = priceBuckets(req.PriceEdges, counts)
+	}
+	return out, nil
+}
+
+func priceBucket(price float64, edges []float64) int {
+	for i, e := range edges {
+		if price < e {
+			return i
+		}
+	}
+	return len(edges)
+}
+
+func priceBuckets(edges []float64, counts []int) []PriceBucket {
+	out := make([]PriceBucket, len(counts))
+	for i := range edges {
+		out[i].Max = &edges[i]
+		out[i+1].Min = &edges[i]
+	}
+	for i, n := range counts {
+		out[i].Count = n
+	}
+	return out
+}
+
+func byCount(m map[string]int) []FacetCount {
+	out := facetCounts(m)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+func byBedrooms(m map[string]int) []FacetCount {
+	out := facetCounts(m)
+	sort.Slice(out, func(i, j int) bool {
+		a, _ := strconv.Atoi(out[i].Value)
+		b, _ := strconv.Atoi(out[j].Value)
+		return a < b
+	})
+	return out
+}
+
+func facetCounts(m map[string]int) []FacetCount {
+	out := make([]FacetCount, 0, len(m))
+	for v, n := range m {
+		out = append(out, FacetCount{Value: v, Count: n})
+	}
+	return out
+}
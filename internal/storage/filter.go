@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// featureColumns are the JSON keys of domain.Features, which are also the
+// names of the typed SQLite columns holding them.
+var featureColumns = domain.FeatureKeys()
+
+// PropertyFilter selects properties for listing and for /match scans. Zero
+// numeric bounds are not set; feature bounds are set by presence in the map.
+// Matches and the SQL built by where must agree on every field.
+type PropertyFilter struct {
+	Locations        []string // location contains any of them, case-insensitive
+	BlockedLocations []string // location contains none of them
+
+	MinPrice, MaxPrice         float64
+	MinBedrooms, MaxBedrooms   int
+	MinBathrooms, MaxBathrooms int
+	MinAreaSQM, MaxAreaSQM     float64
+
+	// price / area_sqm; a property without area never passes these
+	MinPricePerSQM, MaxPricePerSQM float64
+
+	Amenities         []string // all required, case-insensitive
+	AnyAmenities      []string // at least one required
+	ExcludedAmenities []string
+
+	// feature JSON key -> bound; an unknown key matches nothing
+	MinFeatures, MaxFeatures map[string]float64
+
+	Text TextQuery // full-text search over title and description
+
+	// geographic bounds; a property without coordinates never passes them
+	BBox     *BBox
+	Near     *domain.GeoPoint // with RadiusKm: within it; alone only for the distance sort
+	RadiusKm float64
+}
+
+// amenityKey is how amenities are compared and indexed.
+func amenityKey(a string) string {
+	return strings.ToLower(strings.TrimSpace(a))
+}
+
+// locationKey is what location filters search in; SQLite stores it in
+// location_key, since its LOWER only folds ASCII letters.
+func locationKey(l string) string {
+	return strings.ToLower(l)
+}
+
+// Matches reports whether p passes f; it is the in-memory twin of where.
+func (f PropertyFilter) Matches(p domain.Property) bool {
+	location := locationKey(p.Location)
+	if locs := nonEmptyKeys(f.Locations); len(locs) > 0 && !containsAny(location, locs) {
+		return false
+	}
+	if containsAny(location, nonEmptyKeys(f.BlockedLocations)) {
+		return false
+	}
+
+	switch {
+	case f.MinPrice > 0 && p.Price < f.MinPrice,
+		f.MaxPrice > 0 && p.Price > f.MaxPrice,
+		f.MinBedrooms > 0 && p.Bedrooms < f.MinBedrooms,
+		f.MaxBedrooms > 0 && p.Bedrooms > f.MaxBedrooms,
+		f.MinBathrooms > 0 && p.Bathrooms < f.MinBathrooms,
+		f.MaxBathrooms > 0 && p.Bathrooms > f.MaxBathrooms,
+		f.MinAreaSQM > 0 && p.AreaSQM < f.MinAreaSQM,
+		f.MaxAreaSQM > 0 && p.AreaSQM > f.MaxAreaSQM:
+		return false
+	}
+	if f.MinPricePerSQM > 0 || f.MaxPricePerSQM > 0 {
+		if p.AreaSQM <= 0 {
+			return false
+		}
+		ppsqm := p.Price / p.AreaSQM
+		if f.MinPricePerSQM > 0 && ppsqm < f.MinPricePerSQM || f.MaxPricePerSQM > 0 && ppsqm > f.MaxPricePerSQM {
+			return false
+		}
+	}
+
+	if len(f.Amenities) > 0 || len(f.AnyAmenities) > 0 || len(f.ExcludedAmenities) > 0 {
+		have := make(map[string]bool, len(p.Amenities))
+		for _, a := range p.Amenities {
+			have[amenityKey(a)] = true
+		}
+		for _, a := range nonEmptyKeys(f.Amenities) {
+			if !have[a] {
+				return false
+			}
+		}
+		if anyOf := nonEmptyKeys(f.AnyAmenities); len(anyOf) > 0 && !slices.ContainsFunc(anyOf, func(a string) bool { return have[a] }) {
+			return false
+		}
+		for _, a := range nonEmptyKeys(f.ExcludedAmenities) {
+			if have[a] {
+				return false
+			}
+		}
+	}
+
+	if len(f.Text) > 0 && !f.Text.Matches(p) {
+		return false
+	}
+	if !f.matchesGeo(p) {
+		return false
+	}
+
+	for key, min := range f.MinFeatures {
+		if v, ok := domain.FeatureValue(p.Features, key); !ok || v < min {
+			return false
+		}
+	}
+	for key, max := range f.MaxFeatures {
+		if v, ok := domain.FeatureValue(p.Features, key); !ok || v > max {
+			return false
+		}
+	}
+	return true
+}
+
+// where renders f as an SQL condition list over properties.
+func (f PropertyFilter) where() ([]string, []any) {
+	var where []string
+	var args []any
+	add := func(cond string, v ...any) {
+		where = append(where, cond)
+		args = append(args, v...)
+	}
+
+	if locs := nonEmptyKeys(f.Locations); len(locs) > 0 {
+		cond := make([]string, len(locs))
+		for i, l := range locs {
+			cond[i] = "instr(location_key, ?) > 0"
+			args = append(args, l)
+		}
+		where = append(where, "("+strings.Join(cond, " OR ")+")")
+	}
+	for _, l := range nonEmptyKeys(f.BlockedLocations) {
+		add("instr(location_key, ?) = 0", l)
+	}
+
+	if f.MinPrice > 0 {
+		add("price >= ?", f.MinPrice)
+	}
+	if f.MaxPrice > 0 {
+		add("price <= ?", f.MaxPrice)
+	}
+	if f.MinBedrooms > 0 {
+		add("bedrooms >= ?", f.MinBedrooms)
+	}
+	if f.MaxBedrooms > 0 {
+		add("bedrooms <= ?", f.MaxBedrooms)
+	}
+	if f.MinBathrooms > 0 {
+		add("bathrooms >= ?", f.MinBathrooms)
+	}
+	if f.MaxBathrooms > 0 {
+		add("bathrooms <= ?", f.MaxBathrooms)
+	}
+	if f.MinAreaSQM > 0 {
+		add("area_sqm >= ?", f.MinAreaSQM)
+	}
+	if f.MaxAreaSQM > 0 {
+		add("area_sqm <= ?", f.MaxAreaSQM)
+	}
+	if f.MinPricePerSQM > 0 {
+		add("area_sqm > 0 AND price / area_sqm >= ?", f.MinPricePerSQM)
+	}
+	if f.MaxPricePerSQM > 0 {
+		add("area_sqm > 0 AND price / area_sqm <= ?", f.MaxPricePerSQM)
+	}
+
+	for _, a := range nonEmptyKeys(f.Amenities) {
+		add("EXISTS (SELECT 1 FROM property_amenities a WHERE a.property_id = properties.id AND a.name_key = ?)", a)
+	}
+	if anyOf := nonEmptyKeys(f.AnyAmenities); len(anyOf) > 0 {
+		cond := "EXISTS (SELECT 1 FROM property_amenities a WHERE a.property_id = properties.id AND a.name_key IN (?" +
+			strings.Repeat(", ?", len(anyOf)-1) + "))"
+		for _, a := range anyOf {
+			args = append(args, a)
+		}
+		where = append(where, cond)
+	}
+	for _, a := range nonEmptyKeys(f.ExcludedAmenities) {
+		add("NOT EXISTS (SELECT 1 FROM property_amenities a WHERE a.property_id = properties.id AND a.name_key = ?)", a)
+	}
+
+	// iterate columns, not the maps: column names never come from the request
+	for _, key := range featureColumns {
+		if v, ok := f.MinFeatures[key]; ok {
+			add(key+" >= ?", v)
+		}
+		if v, ok := f.MaxFeatures[key]; ok {
+			add(key+" <= ?", v)
+		}
+	}
+	if len(f.Text) > 0 {
+		add("id IN (SELECT s.property_id FROM properties_fts JOIN property_search s ON s.search_id = properties_fts.rowid WHERE properties_fts MATCH ?)", f.Text.match())
+	}
+	geo, geoArgs := f.whereGeo()
+	where, args = append(where, geo...), append(args, geoArgs...)
+	if unknownFeature(f.MinFeatures) || unknownFeature(f.MaxFeatures) {
+		where = append(where, "0")
+	}
+	return where, args
+}
+
+func unknownFeature(bounds map[string]float64) bool {
+	for key := range bounds {
+		if _, ok := domain.FeatureValue(domain.Features{}, key); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmptyKeys normalizes items with amenityKey, dropping empty ones.
+func nonEmptyKeys(items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, it := range items {
+		if k := amenityKey(it); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestPropertyFilter_SQLMatchesGo(t *testing.T) {
+	t.Parallel()
+
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	if err := s.EnsureSchema(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	props := []domain.Property{
+		{ID: "a", Location: "Valencia", Price: 300000, Amenities: []string{"Pool", " Parking"},
+			Features: domain.Features{Quietness: 0.8, DistanceToSeaKm: 1.5}},
+		{ID: "b", Location: "Old Valencia", Price: 500000, Amenities: []string{"parking"},
+			Features: domain.Features{Quietness: 0.4, GreenAreas: 0.9}},
+		{ID: "c", Location: "Madrid", Price: 200000},
+		{ID: "d", Location: "MÁLAGA", Price: 250000, Amenities: []string{"Ático"}},
+		{ID: "e", Location: "Москва", Price: 400000},
+	}
+	if err := s.UpsertMany(props); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	// амениты читаются в исходном порядке и написании
+	got, _, _ := s.GetProperty("a")
+	if !reflect.DeepEqual(got.Amenities, props[0].Amenities) || got.Features != props[0].Features {
+		t.Fatalf("round trip: %+v", got)
+	}
+
+	filters := []PropertyFilter{
+		{},
+		{Amenities: []string{"parking"}},
+		{Amenities: []string{"POOL", "parking"}},
+		{ExcludedAmenities: []string{"pool"}},
+		{Locations: []string{"valencia"}, BlockedLocations: []string{"old"}},
+		{MinFeatures: map[string]float64{"quietness": 0.5}},
+		{MaxFeatures: map[string]float64{"distance_to_sea_km": 1}},
+		{MinFeatures: map[string]float64{"green_areas": 0.5}, MaxPrice: 600000},
+		{MinFeatures: map[string]float64{"nope": 0}},
+		// LOWER в SQLite складывает только ASCII
+		{Locations: []string{"málaga"}},
+		{Locations: []string{"МОСКВА", "madrid"}},
+		{BlockedLocations: []string{"москва", "Málaga"}},
+		{Amenities: []string{"ÁTICO"}},
+	}
+	for _, f := range filters {
+		var want, have []string
+		for _, p := range props {
+			if f.Matches(p) {
+				want = append(want, p.ID)
+			}
+		}
+		err := s.ScanProperties(context.Background(), f, func(p domain.Property) bool {
+			have = append(have, p.ID)
+			return true
+		})
+		if err != nil {
+			t.Fatalf("scan %+v: %v", f, err)
+		}
+		if len(f.Locations) > 0 && len(want) == 0 {
+			t.Fatalf("filter %+v matches nothing", f)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("filter %+v: sql=%v go=%v", f, have, want)
+		}
+	}
+
+	// удаление убирает и строки property_amenities
+	if ok, err := s.DeleteProperty("a"); !ok || err != nil {
+		t.Fatalf("delete: ok=%v err=%v", ok, err)
+	}
+	var n int
+	_ = s.db.QueryRow(`SELECT COUNT(*) FROM property_amenities WHERE property_id = 'a'`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("%d amenity rows left", n)
+	}
+}
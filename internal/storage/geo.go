@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// earthRadiusKm is the mean Earth radius.
+const earthRadiusKm = 6371.0088
+
+// DistanceKm is the great-circle (haversine) distance between a and b.
+func DistanceKm(a, b domain.GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// ValidGeoPoint reports whether p is within [-90, 90] x [-180, 180].
+func ValidGeoPoint(p domain.GeoPoint) bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// BBox is a lat/lon rectangle, bounds included. MinLon > MaxLon means it
+// crosses the antimeridian.
+type BBox struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// Contains reports whether p is inside b.
+func (b BBox) Contains(p domain.GeoPoint) bool {
+	for _, r := range b.split() {
+		if p.Lat >= r.MinLat && p.Lat <= r.MaxLat && p.Lon >= r.MinLon && p.Lon <= r.MaxLon {
+			return true
+		}
+	}
+	return false
+}
+
+// split cuts a box crossing the antimeridian in two ordinary ones.
+func (b BBox) split() []BBox {
+	if b.MinLon <= b.MaxLon {
+		return []BBox{b}
+	}
+	return []BBox{
+		{MinLat: b.MinLat, MinLon: b.MinLon, MaxLat: b.MaxLat, MaxLon: 180},
+		{MinLat: b.MinLat, MinLon: -180, MaxLat: b.MaxLat, MaxLon: b.MaxLon},
+	}
+}
+
+// radiusBox is a box holding every point within km of c; it only narrows the
+// index lookup, the exact test is the distance.
+func radiusBox(c domain.GeoPoint, km float64) BBox {
+	d := km / earthRadiusKm // angular radius
+	dLat := d * 180 / math.Pi
+	b := BBox{MinLat: c.Lat - dLat, MaxLat: c.Lat + dLat, MinLon: -180, MaxLon: 180}
+	ratio := math.Sin(d) / math.Cos(c.Lat*math.Pi/180)
+	if b.MinLat <= -90 || b.MaxLat >= 90 || d >= math.Pi/2 || ratio >= 1 {
+		// the circle holds a pole: every longitude
+		b.MinLat, b.MaxLat = max(b.MinLat, -90), min(b.MaxLat, 90)
+		return b
+	}
+	dLon := math.Asin(ratio) * 180 / math.Pi
+	b.MinLon, b.MaxLon = c.Lon-dLon, c.Lon+dLon
+	switch {
+	case b.MinLon < -180:
+		b.MinLon += 360
+	case b.MaxLon > 180:
+		b.MaxLon -= 360
+	}
+	return b
+}
+
+// matchesGeo is the geographic part of PropertyFilter.Matches.
+func (f PropertyFilter) matchesGeo(p domain.Property) bool {
+	if f.BBox == nil && (f.Near == nil || f.RadiusKm <= 0) {
+		return true
+	}
+	if p.Coordinates == nil {
+		return false
+	}
+	if f.BBox != nil && !f.BBox.Contains(*p.Coordinates) {
+		return false
+	}
+	return f.Near == nil || f.RadiusKm <= 0 || DistanceKm(*f.Near, *p.Coordinates) <= f.RadiusKm
+}
+
+// whereGeo is the geographic part of PropertyFilter.where: the R-tree finds
+// candidates, the lat/lon columns decide exactly.
+func (f PropertyFilter) whereGeo() ([]string, []any) {
+	var where []string
+	var args []any
+	if f.BBox != nil {
+		cond, condArgs := indexedBox(*f.BBox)
+		where = append(where, cond)
+		args = append(args, condArgs...)
+
+		var exact []string
+		for _, r := range f.BBox.split() {
+			exact = append(exact, "(lat BETWEEN ? AND ? AND lon BETWEEN ? AND ?)")
+			args = append(args, r.MinLat, r.MaxLat, r.MinLon, r.MaxLon)
+		}
+		where = append(where, "("+strings.Join(exact, " OR ")+")")
+	}
+	if f.Near != nil && f.RadiusKm > 0 {
+		cond, condArgs := indexedBox(radiusBox(*f.Near, f.RadiusKm))
+		where = append(where, cond, "distance_km(lat, lon, ?, ?) <= ?")
+		args = append(append(args, condArgs...), f.Near.Lat, f.Near.Lon, f.RadiusKm)
+	}
+	return where, args
+}
+
+// indexedBox selects the properties whose point the R-tree places in b.
+func indexedBox(b BBox) (string, []any) {
+	var boxes []string
+	var args []any
+	for _, r := range b.split() {
+		boxes = append(boxes, "(r.max_lat >= ? AND r.min_lat <= ? AND r.max_lon >= ? AND r.min_lon <= ?)")
+		args = append(args, r.MinLat, r.MaxLat, r.MinLon, r.MaxLon)
+	}
+	return `id IN (SELECT g.property_id FROM properties_geo r JOIN property_geo g ON g.geo_id = r.id WHERE ` +
+		strings.Join(boxes, " OR ") + `)`, args
+}
+
+// sqlDistanceKm is the distance_km SQL function (see sqliteDriver).
+func sqlDistanceKm(lat, lon, lat0, lon0 any) any {
+	var v [4]float64
+	for i, arg := range []any{lat, lon, lat0, lon0} {
+		switch x := arg.(type) {
+		case float64:
+			v[i] = x
+		case int64:
+			v[i] = float64(x)
+		default:
+			return nil
+		}
+	}
+	return DistanceKm(domain.GeoPoint{Lat: v[2], Lon: v[3]}, domain.GeoPoint{Lat: v[0], Lon: v[1]})
+}
+
+// distanceSQL is the distance to c as an SQL expression; c is inlined so the
+// expression needs no arguments in ORDER BY.
+func distanceSQL(c *domain.GeoPoint) string {
+	if c == nil {
+		return "NULL"
+	}
+	return "distance_km(lat, lon, " + strconv.FormatFloat(c.Lat, 'g', -1, 64) + ", " +
+		strconv.FormatFloat(c.Lon, 'g', -1, 64) + ")"
+}
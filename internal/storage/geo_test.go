@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestDistanceKm(t *testing.T) {
+	t.Parallel()
+
+	valencia := domain.GeoPoint{Lat: 39.4699, Lon: -0.3763}
+	madrid := domain.GeoPoint{Lat: 40.4168, Lon: -3.7038}
+	if d := DistanceKm(valencia, madrid); math.Abs(d-302) > 2 {
+		t.Fatalf("Valencia-Madrid = %.1f km", d)
+	}
+	// через антимеридиан — короткий путь
+	if d := DistanceKm(domain.GeoPoint{Lon: 179.5}, domain.GeoPoint{Lon: -179.5}); math.Abs(d-111.2) > 0.5 {
+		t.Fatalf("across antimeridian = %.1f km", d)
+	}
+}
+
+func TestGeoFilter_SQLMatchesGo(t *testing.T) {
+	t.Parallel()
+
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	if err := s.EnsureSchema(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	// случайные точки везде, включая полюса и антимеридиан, и объекты без координат
+	rnd := rand.New(rand.NewSource(1))
+	var props []domain.Property
+	for i := 0; i < 400; i++ {
+		p := domain.Property{ID: fmt.Sprintf("p%03d", i), Title: "t", Location: "x", Price: 1}
+		if i%10 != 0 {
+			p.Coordinates = &domain.GeoPoint{Lat: rnd.Float64()*180 - 90, Lon: rnd.Float64()*360 - 180}
+		}
+		props = append(props, p)
+	}
+	props = append(props, domain.Property{ID: "edge", Title: "t", Location: "x", Price: 1,
+		Coordinates: &domain.GeoPoint{Lat: 10, Lon: 20}})
+	if err := s.UpsertMany(props); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	near := func(lat, lon float64) *domain.GeoPoint { return &domain.GeoPoint{Lat: lat, Lon: lon} }
+	filters := []PropertyFilter{
+		{BBox: &BBox{MinLat: 10, MinLon: 20, MaxLat: 40, MaxLon: 60}}, // edge на границе
+		{BBox: &BBox{MinLat: -30, MinLon: 150, MaxLat: 30, MaxLon: -150}},
+		{Near: near(10, 20), RadiusKm: 1500},
+		{Near: near(0, 179), RadiusKm: 2500},
+		{Near: near(85, 0), RadiusKm: 1000},
+		{Near: near(-60, -100), RadiusKm: 8000},
+		{Near: near(40, 10), RadiusKm: 3000, BBox: &BBox{MinLat: 30, MinLon: -10, MaxLat: 90, MaxLon: 30}},
+		{Near: near(40, 10)}, // только для сортировки
+	}
+	for _, f := range filters {
+		var want []domain.Property
+		for _, p := range props {
+			if f.Matches(p) {
+				want = append(want, p)
+			}
+		}
+		page, _ := PageProperties(want, f, Page{Sort: []SortKey{{Field: "distance"}}, Limit: 1000})
+		var wantIDs []string
+		for _, p := range page {
+			wantIDs = append(wantIDs, p.ID)
+		}
+		if len(wantIDs) == 0 || len(wantIDs) == len(props) && f.RadiusKm > 0 {
+			t.Fatalf("%+v: filter selects %d of %d, a useless case", f, len(wantIDs), len(props))
+		}
+
+		got, total, _, err := s.ListPropertiesFiltered(f, Page{Sort: []SortKey{{Field: "distance"}}, Limit: 1000})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		var gotIDs []string
+		for _, p := range got {
+			gotIDs = append(gotIDs, p.ID)
+		}
+		if total != len(wantIDs) || !reflect.DeepEqual(gotIDs, wantIDs) {
+			t.Fatalf("%+v:\nsql  %v\nwant %v", f, gotIDs, wantIDs)
+		}
+	}
+
+	// индекс следует за изменениями и удалениями
+	box := PropertyFilter{BBox: &BBox{MinLat: 9, MinLon: 19, MaxLat: 11, MaxLon: 21}}
+	ids := func() []string {
+		var out []string
+		_ = s.ScanProperties(context.Background(), box, func(p domain.Property) bool {
+			out = append(out, p.ID)
+			return true
+		})
+		return out
+	}
+	if got := ids(); !reflect.DeepEqual(got, []string{"edge"}) {
+		t.Fatalf("box before update: %v", got)
+	}
+	moved := props[len(props)-1]
+	moved.Coordinates = nil
+	if _, _, err := s.UpdateProperty(moved, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := ids(); len(got) != 0 {
+		t.Fatalf("box after clearing coordinates: %v", got)
+	}
+	moved.Coordinates = &domain.GeoPoint{Lat: 10.5, Lon: 20.5}
+	if _, _, err := s.UpdateProperty(moved, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := ids(); !reflect.DeepEqual(got, []string{"edge"}) {
+		t.Fatalf("box after moving back: %v", got)
+	}
+	if _, err := s.DeleteProperty("edge"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var n int
+	_ = s.db.QueryRow(`SELECT COUNT(*) FROM properties_geo`).Scan(&n)
+	if want := 360; n != want {
+		t.Fatalf("R-tree rows = %d, want %d", n, want)
+	}
+
+	// запрос по области идёт через R-tree
+	where, args := box.where()
+	rows, err := s.db.Query(`EXPLAIN QUERY PLAN SELECT id FROM properties `+joinWhere(where), args...)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		_ = rows.Scan(&id, &parent, &unused, &detail)
+		plan = append(plan, detail)
+	}
+	if !strings.Contains(strings.Join(plan, "\n"), "VIRTUAL TABLE INDEX") {
+		t.Fatalf("plan does not use the R-tree:\n%s", strings.Join(plan, "\n"))
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// PropertyIDPrefix marks IDs issued by NewPropertyID; seed and client IDs keep their own form.
+const PropertyIDPrefix = "p-"
+
+// crockford is the ULID alphabet: no I, L, O, U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var idGen struct {
+	mu     sync.Mutex
+	lastMS uint64
+	hi     uint16 // top 16 of the 80 random bits
+	lo     uint64 // low 64 random bits
+}
+
+// NewPropertyID returns "p-" followed by a ULID: 48-bit millisecond time and
+// 80 random bits in Crockford base32. IDs sort by creation time; within one
+// millisecond the random part is incremented, so they stay strictly ordered.
+func NewPropertyID() string {
+	idGen.mu.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= idGen.lastMS {
+		ms = idGen.lastMS
+		idGen.lo++
+		if idGen.lo == 0 {
+			idGen.hi++
+		}
+	} else {
+		var b [10]byte
+		_, _ = rand.Read(b[:])
+		idGen.lastMS = ms
+		idGen.hi = binary.BigEndian.Uint16(b[:2])
+		idGen.lo = binary.BigEndian.Uint64(b[2:])
+	}
+	hi, lo := idGen.hi, idGen.lo
+	idGen.mu.Unlock()
+
+	// 128 bits: 48 time | 16 hi | 64 lo, encoded as 26 chars (the first carries 3 bits).
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | uint64(hi)<<59
+		hi = hi>>5 | uint16(ms<<11)
+		ms >>= 5
+	}
+	return PropertyIDPrefix + string(out[:])
+}
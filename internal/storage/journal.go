@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// journal is an append-only log of MemoryStore mutations, one JSON object per
+// line. Entries carry the whole property, so replaying one twice is harmless.
+type journal struct {
+	f            *os.File
+	snapshotPath string
+	entries      int // appended since the last compaction
+}
+
+type journalEntry struct {
+	Op       string           `json:"op"` // put | delete
+	ID       string           `json:"id"`
+	Property *domain.Property `json:"property,omitempty"`
+}
+
+// OpenJournaledStore builds a MemoryStore that survives restarts. It loads
+// snapshotPath, or seedPath while no snapshot exists yet, replays the journal
+// on top and then appends every mutation to the journal before applying it.
+// Compact folds the journal into a new snapshot.
+func OpenJournaledStore(seedPath, snapshotPath, journalPath string) (*MemoryStore, error) {
+	base := snapshotPath
+	if _, err := os.Stat(snapshotPath); errors.Is(err, os.ErrNotExist) {
+		base = seedPath
+	}
+	items, err := LoadPropertiesFromFile(base)
+	if err != nil {
+		return nil, err
+	}
+	s := NewMemoryStore(items)
+
+	f, err := os.OpenFile(journalPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	n, err := s.replay(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("replay journal %s: %w", journalPath, err)
+	}
+	s.journal = &journal{f: f, snapshotPath: snapshotPath, entries: n}
+	return s, nil
+}
+
+// replay applies the journal to the store and leaves f positioned at the end
+// of the last complete entry. A torn last line (crash mid-append) is cut off.
+func (s *MemoryStore) replay(f *os.File) (int, error) {
+	items := append([]domain.Property(nil), s.Snapshot()...)
+	r := bufio.NewReader(f)
+	var good int64
+	n := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break // an unterminated tail is an incomplete write
+		}
+		if err != nil {
+			return 0, err
+		}
+		var e journalEntry
+		if jerr := json.Unmarshal(bytes.TrimSpace(line), &e); jerr != nil {
+			if _, perr := r.Peek(1); errors.Is(perr, io.EOF) {
+				break
+			}
+			return 0, fmt.Errorf("entry %d: %w", n+1, jerr)
+		}
+		switch {
+		case e.Op == "put" && e.Property != nil:
+			if i := indexOfProperty(items, e.Property.ID); i >= 0 {
+				items[i] = *e.Property
+			} else {
+				items = append(items, *e.Property)
+			}
+		case e.Op == "delete":
+			if i := indexOfProperty(items, e.ID); i >= 0 {
+				items = append(items[:i], items[i+1:]...)
+			}
+		default:
+			return 0, fmt.Errorf("entry %d: unknown op %q", n+1, e.Op)
+		}
+		good += int64(len(line))
+		n++
+	}
+	if err := f.Truncate(good); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		return 0, err
+	}
+	s.reset(items)
+	return n, nil
+}
+
+func (j *journal) put(p domain.Property) error {
+	return j.append(journalEntry{Op: "put", ID: p.ID, Property: &p})
+}
+
+func (j *journal) delete(id string) error {
+	return j.append(journalEntry{Op: "delete", ID: id})
+}
+
+// append writes and syncs one entry; a nil journal (no persistence) is a no-op.
+func (j *journal) append(e journalEntry) error {
+	if j == nil {
+		return nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	off, err := j.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("journal offset: %w", err)
+	}
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return j.rollback(off, fmt.Errorf("journal write: %w", err))
+	}
+	if err := j.f.Sync(); err != nil {
+		return j.rollback(off, fmt.Errorf("journal sync: %w", err))
+	}
+	j.entries++
+	return nil
+}
+
+// rollback cuts a failed append back to off, so a partial line cannot end up
+// in the middle of the journal once the next append succeeds.
+func (j *journal) rollback(off int64, cause error) error {
+	if err := j.f.Truncate(off); err != nil {
+		return errors.Join(cause, fmt.Errorf("journal rollback: %w", err))
+	}
+	if _, err := j.f.Seek(off, io.SeekStart); err != nil {
+		return errors.Join(cause, fmt.Errorf("journal rollback: %w", err))
+	}
+	return cause
+}
+
+// Compact writes the current properties to the snapshot file and empties the
+// journal. Writers are blocked meanwhile, so no mutation falls in between.
+// It does nothing without a journal or when the journal is already empty.
+func (s *MemoryStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j := s.journal
+	if j == nil || j.entries == 0 {
+		return nil
+	}
+	if err := writeFileAtomic(j.snapshotPath, s.Snapshot()); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	// A crash before the truncate only means the journal is replayed over a
+	// snapshot that already contains it, which is idempotent.
+	if err := j.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.entries = 0
+	return nil
+}
+
+// RunCompaction calls Compact every interval until ctx is done.
+func (s *MemoryStore) RunCompaction(ctx context.Context, interval time.Duration, logf func(format string, args ...any)) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := s.Compact(); err != nil {
+			logf("journal compaction failed: %v", err)
+		}
+	}
+}
+
+// Close closes the journal file, if any.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.f.Close()
+}
+
+// writeFileAtomic writes items as JSON to a temp file next to path, renames it
+// over path and syncs the directory so the rename itself survives a crash.
+func writeFileAtomic(path string, items []domain.Property) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(items); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return fmt.Errorf("sync %s: %w", dir, err)
+	}
+	return d.Close()
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func writeSeed(t *testing.T, dir string, items []domain.Property) string {
+	t.Helper()
+	path := filepath.Join(dir, "properties.json")
+	b, _ := json.Marshal(items)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	return path
+}
+
+func ids(items []domain.Property) []string {
+	out := make([]string, 0, len(items))
+	for _, p := range items {
+		out = append(out, p.ID)
+	}
+	return out
+}
+
+func TestJournaledStore_ReplayAndCompact(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	seed := writeSeed(t, dir, []domain.Property{{ID: "es-001", Price: 1}, {ID: "es-002", Price: 2}})
+	snap := filepath.Join(dir, "state.json")
+	jpath := filepath.Join(dir, "journal.log")
+
+	s, err := OpenJournaledStore(seed, snap, jpath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	created, err := s.CreateProperty(domain.Property{ID: "ext-1", Price: 3})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, _, err := s.UpdateProperty(domain.Property{ID: "es-001", Price: 10}, 1); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := s.DeleteProperty("es-002"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	_ = s.Close()
+
+	// рестарт: seed + журнал
+	s, err = OpenJournaledStore(seed, snap, jpath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got := s.Snapshot()
+	if len(got) != 2 || got[0].ID != "es-001" || got[0].Price != 10 || got[0].Version != 2 || got[1].ID != created.ID {
+		t.Fatalf("after replay: %+v", got)
+	}
+
+	// compaction writes the snapshot and empties the journal
+	if err := s.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if fi, _ := os.Stat(jpath); fi.Size() != 0 {
+		t.Fatalf("journal size=%d after compaction", fi.Size())
+	}
+	if _, err := s.CreateProperty(domain.Property{ID: "ext-2"}); err != nil {
+		t.Fatalf("create after compact: %v", err)
+	}
+	_ = s.Close()
+
+	// snapshot wins over the seed; the journal after it still applies
+	s, err = OpenJournaledStore(seed, snap, jpath)
+	if err != nil {
+		t.Fatalf("reopen after compaction: %v", err)
+	}
+	defer s.Close()
+	if got := ids(s.Snapshot()); len(got) != 3 || got[0] != "es-001" || got[2] != "ext-2" {
+		t.Fatalf("after compaction: %v", got)
+	}
+}
+
+func TestJournaledStore_TornTailAndReplayTwice(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	seed := writeSeed(t, dir, []domain.Property{{ID: "a"}})
+	jpath := filepath.Join(dir, "journal.log")
+	// a put replayed twice (crash between snapshot and truncate) plus a half-written last line
+	journal := `{"op":"put","id":"b","property":{"id":"b","version":1}}
+{"op":"put","id":"b","property":{"id":"b","version":1}}
+{"op":"delete","id":"a"}
+{"op":"put","id":"c","prop`
+	if err := os.WriteFile(jpath, []byte(journal), 0o644); err != nil {
+		t.Fatalf("write journal: %v", err)
+	}
+
+	s, err := OpenJournaledStore(seed, filepath.Join(dir, "state.json"), jpath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if got := ids(s.Snapshot()); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("items=%v want [b]", got)
+	}
+	// the torn tail is cut, new entries start on a clean line
+	if _, err := s.CreateProperty(domain.Property{ID: "d"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_ = s.Close()
+	s, err = OpenJournaledStore(seed, filepath.Join(dir, "state.json"), jpath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	if got := ids(s.Snapshot()); len(got) != 2 || got[1] != "d" {
+		t.Fatalf("items=%v want [b d]", got)
+	}
+}
+
+func TestJournaledStore_CorruptEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	seed := writeSeed(t, dir, nil)
+	jpath := filepath.Join(dir, "journal.log")
+	if err := os.WriteFile(jpath, []byte("garbage\n{\"op\":\"delete\",\"id\":\"a\"}\n"), 0o644); err != nil {
+		t.Fatalf("write journal: %v", err)
+	}
+	if _, err := OpenJournaledStore(seed, filepath.Join(dir, "state.json"), jpath); err == nil {
+		t.Fatalf("corrupt entry in the middle must fail")
+	}
+}
+
+func TestJournal_RollbackCutsPartialEntry(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.OpenFile(filepath.Join(t.TempDir(), "journal.log"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	j := &journal{f: f}
+	if err := j.delete("a"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	off, _ := f.Seek(0, io.SeekCurrent)
+
+	// a write that stopped half-way through the line
+	if _, err := f.Write([]byte(`{"op":"put","id":"b","prop`)); err != nil {
+		t.Fatalf("partial write: %v", err)
+	}
+	cause := errors.New("disk full")
+	if err := j.rollback(off, cause); !errors.Is(err, cause) {
+		t.Fatalf("rollback err=%v", err)
+	}
+	if err := j.delete("c"); err != nil {
+		t.Fatalf("append after rollback: %v", err)
+	}
+	b, _ := os.ReadFile(f.Name())
+	want := "{\"op\":\"delete\",\"id\":\"a\"}\n{\"op\":\"delete\",\"id\":\"c\"}\n"
+	if string(b) != want || j.entries != 2 {
+		t.Fatalf("journal=%q entries=%d", b, j.entries)
+	}
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// MemoryStore keeps properties in memory. Writers are serialized and publish
+// a fresh copy of the state (copy-on-write), so readers take a snapshot with
+// one atomic load and never block or see a half-applied change.
+type MemoryStore struct {
+	mu      sync.Mutex // serializes writers
+	state   atomic.Pointer[memState]
+	nextSeq uint64   // guarded by mu
+	journal *journal // nil unless opened with OpenJournaledStore
+}
+
+// memState is immutable once stored. Properties keep the position they were
+// added at, so seqs is increasing and gives the insertion order a key that
+// survives deletes.
+type memState struct {
+	items []domain.Property
+	seqs  []uint64 // insertion sequence of items[i]
+}
+
+// NewMemoryStore copies items into a new store; items without a version start at 1.
+func NewMemoryStore(items []domain.Property) *MemoryStore {
+	cp := make([]domain.Property, len(items))
+	copy(cp, items)
+	for i := range cp {
+		if cp[i].Version <= 0 {
+			cp[i].Version = 1
+		}
+	}
+	s := &MemoryStore{}
+	s.reset(cp)
+	return s
+}
+
+// reset publishes items in their current order with fresh sequence numbers.
+func (s *MemoryStore) reset(items []domain.Property) {
+	seqs := make([]uint64, len(items))
+	for i := range seqs {
+		s.nextSeq++
+		seqs[i] = s.nextSeq
+	}
+	s.state.Store(&memState{items: items, seqs: seqs})
+}
+
+// Snapshot returns the current properties in insertion order. The slice is
+// shared and must not be modified.
+func (s *MemoryStore) Snapshot() []domain.Property {
+	return s.state.Load().items
+}
+
+// ListPropertiesFiltered is the in-memory twin of
+// SQLiteStore.ListPropertiesFiltered, except that without a sort or a text
+// query the properties keep their insertion order.
+func (s *MemoryStore) ListPropertiesFiltered(f PropertyFilter, pg Page) ([]domain.Property, int, *Cursor) {
+	st := s.state.Load()
+	var (
+		items []domain.Property
+		seqs  []uint64
+	)
+	for i, p := range st.items {
+		if f.Matches(p) {
+			items = append(items, p)
+			seqs = append(seqs, st.seqs[i])
+		}
+	}
+	if len(effectiveSort(pg.Sort, f)) > 0 {
+		page, next := PageProperties(items, f, pg)
+		return page, len(items), next
+	}
+
+	start := min(max(pg.Offset, 0), len(items))
+	if pg.Cursor != nil {
+		after := uint64(pg.Cursor.Values[0].(float64))
+		if i := indexOfProperty(st.items, pg.Cursor.ID); i >= 0 {
+			after = st.seqs[i] // still there: exact even if sequence numbers changed on restart
+		}
+		start, _ = slices.BinarySearch(seqs, after+1)
+	}
+	end := min(start+pageLimit(pg.Limit), len(items))
+	var next *Cursor
+	if end < len(items) && end > start {
+		next = &Cursor{Order: insertionOrder, Values: []any{float64(seqs[end-1])}, ID: items[end-1].ID}
+	}
+	return items[start:end], len(items), next
+}
+
+func (s *MemoryStore) CountProperties() int {
+	return len(s.Snapshot())
+}
+
+func (s *MemoryStore) GetProperty(id string) (domain.Property, bool) {
+	items := s.Snapshot()
+	if i := indexOfProperty(items, id); i >= 0 {
+		return items[i], true
+	}
+	return domain.Property{}, false
+}
+
+// CreateProperty stores p as version 1, assigning an id when p.ID is empty.
+func (s *MemoryStore) CreateProperty(p domain.Property) (domain.Property, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state.Load()
+	cur := st.items
+	if p.ID == "" {
+		p.ID = NewPropertyID()
+	} else if indexOfProperty(cur, p.ID) >= 0 {
+		return domain.Property{}, ErrDuplicateID
+	}
+	p.Version, p.UpdatedAt = 1, time.Now().UTC()
+	p.CreatedAt = p.UpdatedAt
+	if err := s.journal.put(p); err != nil {
+		return domain.Property{}, err
+	}
+
+	s.nextSeq++
+	s.state.Store(&memState{
+		items: append(cur[:len(cur):len(cur)], p),
+		seqs:  append(st.seqs[:len(st.seqs):len(st.seqs)], s.nextSeq),
+	})
+	return p, nil
+}
+
+// UpdateProperty behaves like SQLiteStore.UpdateProperty.
+func (s *MemoryStore) UpdateProperty(p domain.Property, ifVersion int64) (domain.Property, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state.Load()
+	cur := st.items
+	i := indexOfProperty(cur, p.ID)
+	if i < 0 {
+		return domain.Property{}, false, nil
+	}
+	if ifVersion > 0 && cur[i].Version != ifVersion {
+		return domain.Property{}, true, ErrVersionConflict
+	}
+	p.Version, p.UpdatedAt = cur[i].Version+1, time.Now().UTC()
+	p.CreatedAt = cur[i].CreatedAt
+	if err := s.journal.put(p); err != nil {
+		return domain.Property{}, false, err
+	}
+
+	next := make([]domain.Property, len(cur))
+	copy(next, cur)
+	next[i] = p
+	s.state.Store(&memState{items: next, seqs: st.seqs})
+	return p, true, nil
+}
+
+func (s *MemoryStore) DeleteProperty(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state.Load()
+	i := indexOfProperty(st.items, id)
+	if i < 0 {
+		return false, nil
+	}
+	if err := s.journal.delete(id); err != nil {
+		return false, err
+	}
+	s.state.Store(&memState{
+		items: slices.Delete(slices.Clone(st.items), i, i+1),
+		seqs:  slices.Delete(slices.Clone(st.seqs), i, i+1),
+	})
+	return true, nil
+}
+
+func indexOfProperty(items []domain.Property, id string) int {
+	for i := range items {
+		if items[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,469 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is one numbered schema change. Migrations run in Version order,
+// each in its own transaction together with its schema_migrations row.
+// Never edit a released migration; append a new one instead.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string              // executed as is, may hold several statements
+	Fn      func(*sql.Tx) error // for changes SQL alone can't express; runs after SQL
+}
+
+// migrations is the schema history; the last entry is the current version.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create properties",
+		// IF NOT EXISTS: databases created before migrations already have these.
+		SQL: `
+CREATE TABLE IF NOT EXISTS properties (
+  id TEXT PRIMARY KEY,
+  title TEXT NOT NULL,
+  location TEXT NOT NULL,
+  price REAL NOT NULL,
+  bedrooms INTEGER NOT NULL,
+  bathrooms INTEGER NOT NULL,
+  area_sqm REAL NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  image_urls_json TEXT NOT NULL DEFAULT '[]',
+  amenities_json TEXT NOT NULL DEFAULT '[]',
+  features_json TEXT NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS idx_properties_location ON properties(location);
+CREATE INDEX IF NOT EXISTS idx_properties_price ON properties(price);
+`,
+	},
+	{
+		Version: 2,
+		Name:    "property version and updated_at",
+		// some databases got these columns before migrations existed
+		Fn: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "properties", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "properties", "updated_at", "TEXT NOT NULL DEFAULT ''")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "typed features and property_amenities",
+		SQL: `
+ALTER TABLE properties ADD COLUMN quietness REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN sun_exposure REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN wind_protection REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN tourism_intensity REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN family_friendly REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN expat_friendly REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN investment_potential REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN distance_to_sea_km REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN walkability REAL NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN green_areas REAL NOT NULL DEFAULT 0;
+UPDATE properties SET
+  quietness = COALESCE(json_extract(features_json, '$.quietness'), 0),
+  sun_exposure = COALESCE(json_extract(features_json, '$.sun_exposure'), 0),
+  wind_protection = COALESCE(json_extract(features_json, '$.wind_protection'), 0),
+  tourism_intensity = COALESCE(json_extract(features_json, '$.tourism_intensity'), 0),
+  family_friendly = COALESCE(json_extract(features_json, '$.family_friendly'), 0),
+  expat_friendly = COALESCE(json_extract(features_json, '$.expat_friendly'), 0),
+  investment_potential = COALESCE(json_extract(features_json, '$.investment_potential'), 0),
+  distance_to_sea_km = COALESCE(json_extract(features_json, '$.distance_to_sea_km'), 0),
+  walkability = COALESCE(json_extract(features_json, '$.walkability'), 0),
+  green_areas = COALESCE(json_extract(features_json, '$.green_areas'), 0);
+CREATE INDEX idx_properties_quietness ON properties(quietness);
+CREATE INDEX idx_properties_distance_to_sea_km ON properties(distance_to_sea_km);
+
+-- name as stored, name_key = lower(trim(name)) for filtering
+CREATE TABLE property_amenities (
+  property_id TEXT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  name_key TEXT NOT NULL,
+  PRIMARY KEY (property_id, position)
+);
+CREATE INDEX idx_property_amenities_name_key ON property_amenities(name_key, property_id);
+INSERT INTO property_amenities (property_id, position, name, name_key)
+SELECT p.id, j.key, j.value, LOWER(TRIM(j.value))
+FROM properties p, json_each(CASE WHEN json_valid(p.amenities_json) THEN p.amenities_json ELSE '[]' END) j
+WHERE j.type = 'text';
+
+ALTER TABLE properties DROP COLUMN features_json;
+ALTER TABLE properties DROP COLUMN amenities_json;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "full-text index on title and description",
+		Fn:      createSearchIndexByID,
+	},
+	{
+		Version: 5,
+		Name:    "property created_at",
+		SQL: `
+ALTER TABLE properties ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_properties_created_at ON properties(created_at);
+`,
+		// best known creation time: the last update, rewritten in the sortable layout
+		Fn: func(tx *sql.Tx) error {
+			rows, err := tx.Query(`SELECT id, updated_at FROM properties`)
+			if err != nil {
+				return err
+			}
+			stamps := make(map[string]string)
+			for rows.Next() {
+				var id, at string
+				if err := rows.Scan(&id, &at); err != nil {
+					rows.Close()
+					return err
+				}
+				t, _ := time.Parse(time.RFC3339Nano, at)
+				stamps[id] = formatTime(t)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			for id, at := range stamps {
+				if _, err := tx.Exec(`UPDATE properties SET created_at = ? WHERE id = ?`, at, id); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "property coordinates and spatial index",
+		// properties_geo is an R-tree of points keyed by property_geo.geo_id, an
+		// INTEGER PRIMARY KEY, since properties rowids may change on VACUUM.
+		SQL: `
+ALTER TABLE properties ADD COLUMN lat REAL;
+ALTER TABLE properties ADD COLUMN lon REAL;
+CREATE TABLE property_geo (
+  geo_id INTEGER PRIMARY KEY,
+  property_id TEXT NOT NULL UNIQUE REFERENCES properties(id) ON DELETE CASCADE
+);
+CREATE VIRTUAL TABLE properties_geo USING rtree(id, min_lat, max_lat, min_lon, max_lon);
+-- a property_geo row owns its R-tree entry, however the row goes (cascade included)
+CREATE TRIGGER property_geo_delete AFTER DELETE ON property_geo BEGIN
+  DELETE FROM properties_geo WHERE id = old.geo_id;
+END;
+CREATE TRIGGER properties_geo_insert AFTER INSERT ON properties
+WHEN new.lat IS NOT NULL AND new.lon IS NOT NULL BEGIN
+  INSERT INTO property_geo (property_id) VALUES (new.id);
+  INSERT INTO properties_geo (id, min_lat, max_lat, min_lon, max_lon)
+  SELECT geo_id, new.lat, new.lat, new.lon, new.lon FROM property_geo WHERE property_id = new.id;
+END;
+CREATE TRIGGER properties_geo_delete AFTER DELETE ON properties BEGIN
+  DELETE FROM property_geo WHERE property_id = old.id;
+END;
+CREATE TRIGGER properties_geo_update AFTER UPDATE OF lat, lon ON properties BEGIN
+  DELETE FROM property_geo WHERE property_id = old.id;
+  INSERT INTO property_geo (property_id) SELECT new.id WHERE new.lat IS NOT NULL AND new.lon IS NOT NULL;
+  INSERT INTO properties_geo (id, min_lat, max_lat, min_lon, max_lon)
+  SELECT geo_id, new.lat, new.lat, new.lon, new.lon FROM property_geo WHERE property_id = new.id;
+END;
+`,
+	},
+	{
+		Version: 7,
+		Name:    "rowid-keyed full-text index",
+		// version 4 deleted index rows by the unindexed id, scanning the
+		// whole index on every write
+		SQL: `
+DROP TRIGGER properties_fts_insert;
+DROP TRIGGER properties_fts_delete;
+DROP TRIGGER properties_fts_update;
+DROP TABLE properties_fts;
+`,
+		Fn: createSearchIndexByRowid,
+	},
+	{
+		Version: 8,
+		Name:    "Unicode-lowercased location and amenity keys",
+		// SQLite's LOWER only folds ASCII, so location filters missed "MÁLAGA"
+		// for "málaga"; the keys are now computed in Go on every write
+		SQL: `ALTER TABLE properties ADD COLUMN location_key TEXT NOT NULL DEFAULT '';`,
+		Fn:  fillLowercaseKeys,
+	},
+	{
+		Version: 9,
+		Name:    "full-text index keyed by property_search",
+		// version 7 keyed index rows by properties.rowid, which may change on
+		// VACUUM like the rowids version 6 avoids for the R-tree
+		SQL: `
+DROP TRIGGER properties_fts_insert;
+DROP TRIGGER properties_fts_delete;
+DROP TRIGGER properties_fts_update;
+DROP TABLE properties_fts;
+`,
+		Fn: createSearchIndex,
+	},
+}
+
+// MigrationStatus is a known migration and when it was applied (nil = pending).
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TEXT NOT NULL
+);
+`
+
+// Migrate applies pending migrations in order and returns the ones it applied.
+// It stops at the first failure; that migration is rolled back entirely.
+func (s *SQLiteStore) Migrate() ([]Migration, error) {
+	if _, err := s.db.Exec(createMigrationsTable); err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, m := range migrations {
+		ok, err := s.applyMigration(m)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// applyMigration runs m unless it is already recorded. The check happens
+// inside the transaction, so two processes starting together apply it once.
+func (s *SQLiteStore) applyMigration(m Migration) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.Version).Scan(&n); err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return false, nil
+	}
+	if m.SQL != "" {
+		if _, err := tx.Exec(m.SQL); err != nil {
+			return false, err
+		}
+	}
+	if m.Fn != nil {
+		if err := m.Fn(tx); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, formatTime(time.Now())); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// MigrationStatus lists every known migration with its applied time.
+func (s *SQLiteStore) MigrationStatus() ([]MigrationStatus, error) {
+	if _, err := s.db.Exec(createMigrationsTable); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var v int
+		var at string
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[v], _ = time.Parse(time.RFC3339Nano, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := appliedAt[m.Version]; ok {
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// SchemaVersion returns the latest known migration version.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// createSearchIndexByID is the version 4 index: plain FTS5, or FTS4 without
+// the sqlite_fts5 tag, keyed by the unindexed property id. Superseded by
+// createSearchIndex in version 7.
+func createSearchIndexByID(tx *sql.Tx) error {
+	fts5, err := fts5Available(tx)
+	if err != nil {
+		return err
+	}
+	table := `CREATE VIRTUAL TABLE properties_fts USING fts4(id, title, description, notindexed=id, tokenize=unicode61 "remove_diacritics=0")`
+	if fts5 {
+		table = `CREATE VIRTUAL TABLE properties_fts USING fts5(id UNINDEXED, title, description, tokenize='unicode61 remove_diacritics 0')`
+	}
+	_, err = tx.Exec(table + `;
+CREATE TRIGGER properties_fts_insert AFTER INSERT ON properties BEGIN
+  INSERT INTO properties_fts (id, title, description) VALUES (new.id, new.title, new.description);
+END;
+CREATE TRIGGER properties_fts_delete AFTER DELETE ON properties BEGIN
+  DELETE FROM properties_fts WHERE id = old.id;
+END;
+CREATE TRIGGER properties_fts_update AFTER UPDATE OF title, description ON properties BEGIN
+  DELETE FROM properties_fts WHERE id = old.id;
+  INSERT INTO properties_fts (id, title, description) VALUES (new.id, new.title, new.description);
+END;
+INSERT INTO properties_fts (id, title, description) SELECT id, title, description FROM properties;
+`)
+	return err
+}
+
+// createSearchIndexByRowid is the version 7 index, keyed by properties.rowid.
+// Superseded by createSearchIndex in version 9.
+func createSearchIndexByRowid(tx *sql.Tx) error {
+	fts5, err := fts5Available(tx)
+	if err != nil {
+		return err
+	}
+	table := `CREATE VIRTUAL TABLE properties_fts USING fts4(title, description, tokenize=unicode61 "remove_diacritics=0")`
+	if fts5 {
+		table = `CREATE VIRTUAL TABLE properties_fts USING fts5(title, description, tokenize='unicode61 remove_diacritics 0')`
+	}
+	_, err = tx.Exec(table + `;
+CREATE TRIGGER properties_fts_insert AFTER INSERT ON properties BEGIN
+  INSERT INTO properties_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+CREATE TRIGGER properties_fts_delete AFTER DELETE ON properties BEGIN
+  DELETE FROM properties_fts WHERE rowid = old.rowid;
+END;
+CREATE TRIGGER properties_fts_update AFTER UPDATE OF title, description ON properties BEGIN
+  DELETE FROM properties_fts WHERE rowid = old.rowid;
+  INSERT INTO properties_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+INSERT INTO properties_fts (rowid, title, description) SELECT rowid, title, description FROM properties;
+`)
+	return err
+}
+
+// fillLowercaseKeys sets location_key and recomputes the amenity name_key
+// that version 3 filled with SQL LOWER.
+func fillLowercaseKeys(tx *sql.Tx) error {
+	keys := make(map[string]string)
+	if err := collectKeys(tx, `SELECT id, location FROM properties`, locationKey, keys); err != nil {
+		return err
+	}
+	for id, key := range keys {
+		if _, err := tx.Exec(`UPDATE properties SET location_key = ? WHERE id = ?`, key, id); err != nil {
+			return err
+		}
+	}
+	names := make(map[string]string)
+	if err := collectKeys(tx, `SELECT DISTINCT name, name FROM property_amenities`, amenityKey, names); err != nil {
+		return err
+	}
+	for name, key := range names {
+		if _, err := tx.Exec(`UPDATE property_amenities SET name_key = ? WHERE name = ?`, key, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectKeys maps the first column of query's rows to key(second column).
+func collectKeys(tx *sql.Tx, query string, key func(string) string, out map[string]string) error {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return err
+		}
+		out[k] = key(v)
+	}
+	return rows.Err()
+}
+
+// createSearchIndex creates properties_fts with FTS5, or FTS4 when the driver
+// was built without the sqlite_fts5 tag. Index rows are keyed by
+// property_search.search_id, an INTEGER PRIMARY KEY and so stable, and the
+// triggers keeping them in sync are key lookups.
+func createSearchIndex(tx *sql.Tx) error {
+	fts5, err := fts5Available(tx)
+	if err != nil {
+		return err
+	}
+	table := `CREATE VIRTUAL TABLE properties_fts USING fts4(title, description, tokenize=unicode61 "remove_diacritics=0")`
+	if fts5 {
+		table = `CREATE VIRTUAL TABLE properties_fts USING fts5(title, description, tokenize='unicode61 remove_diacritics 0')`
+	}
+	_, err = tx.Exec(table + `;
+CREATE TABLE property_search (
+  search_id INTEGER PRIMARY KEY,
+  property_id TEXT NOT NULL UNIQUE REFERENCES properties(id) ON DELETE CASCADE
+);
+-- a property_search row owns its index row, however the row goes (cascade included)
+CREATE TRIGGER property_search_delete AFTER DELETE ON property_search BEGIN
+  DELETE FROM properties_fts WHERE rowid = old.search_id;
+END;
+CREATE TRIGGER properties_fts_insert AFTER INSERT ON properties BEGIN
+  INSERT INTO property_search (property_id) VALUES (new.id);
+  INSERT INTO properties_fts (rowid, title, description)
+  SELECT search_id, new.title, new.description FROM property_search WHERE property_id = new.id;
+END;
+CREATE TRIGGER properties_fts_delete AFTER DELETE ON properties BEGIN
+  DELETE FROM property_search WHERE property_id = old.id;
+END;
+CREATE TRIGGER properties_fts_update AFTER UPDATE OF title, description ON properties BEGIN
+  DELETE FROM properties_fts WHERE rowid = (SELECT search_id FROM property_search WHERE property_id = new.id);
+  INSERT INTO properties_fts (rowid, title, description)
+  SELECT search_id, new.title, new.description FROM property_search WHERE property_id = new.id;
+END;
+INSERT INTO property_search (property_id) SELECT id FROM properties;
+INSERT INTO properties_fts (rowid, title, description)
+SELECT s.search_id, p.title, p.description FROM properties p JOIN property_search s ON s.property_id = p.id;
+`)
+	return err
+}
+
+func fts5Available(tx *sql.Tx) (bool, error) {
+	var fts5 bool
+	err := tx.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5)
+	return fts5, err
+}
+
+func addColumnIfMissing(tx *sql.Tx, table, column, decl string) error {
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl))
+	return err
+}
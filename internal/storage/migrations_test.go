@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// v1Schema is the properties table as created before migrations existed.
+const v1Schema = `
+CREATE TABLE properties (
+  id TEXT PRIMARY KEY,
+  title TEXT NOT NULL,
+  location TEXT NOT NULL,
+  price REAL NOT NULL,
+  bedrooms INTEGER NOT NULL,
+  bathrooms INTEGER NOT NULL,
+  area_sqm REAL NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  image_urls_json TEXT NOT NULL DEFAULT '[]',
+  amenities_json TEXT NOT NULL DEFAULT '[]',
+  features_json TEXT NOT NULL DEFAULT '{}'
+);
+CREATE INDEX idx_properties_location ON properties(location);
+CREATE INDEX idx_properties_price ON properties(price);
+INSERT INTO properties (id, title, location, price, bedrooms, bathrooms, area_sqm, amenities_json, features_json)
+VALUES ('es-001', 'Sunny flat', 'Valencia', 320000, 3, 2, 110, '["parking"]', '{"quietness":0.6,"distance_to_sea_km":1.5}'),
+       ('es-002', 'Loft', 'MÁLAGA', 250000, 1, 1, 60, '["Ático"]', '{}');
+`
+
+func TestMigrate_UpgradesV1Database(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "v1.db")
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open raw: %v", err)
+	}
+	if _, err := raw.Exec(v1Schema); err != nil {
+		t.Fatalf("v1 schema: %v", err)
+	}
+	_ = raw.Close()
+
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+
+	st, err := s.MigrationStatus()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, m := range st {
+		if m.AppliedAt != nil {
+			t.Fatalf("migration %d applied before Migrate", m.Version)
+		}
+	}
+
+	applied, err := s.Migrate()
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %d of %d migrations", len(applied), len(migrations))
+	}
+	if again, err := s.Migrate(); err != nil || len(again) != 0 {
+		t.Fatalf("second Migrate applied=%d err=%v", len(again), err)
+	}
+
+	st, _ = s.MigrationStatus()
+	if len(st) == 0 || st[len(st)-1].Version != SchemaVersion() || st[len(st)-1].AppliedAt == nil {
+		t.Fatalf("status after migrate: %+v", st)
+	}
+
+	// старые данные на месте и читаются новым кодом
+	p, ok, err := s.GetProperty("es-001")
+	if err != nil || !ok {
+		t.Fatalf("get: ok=%v err=%v", ok, err)
+	}
+	if p.Version != 1 || p.Features.Quietness != 0.6 || len(p.Amenities) != 1 {
+		t.Fatalf("upgraded row: %+v", p)
+	}
+	// ключи для фильтров по локации и удобствам заполнены Go-шным ToLower
+	var keyed []string
+	err = s.ScanProperties(context.Background(), PropertyFilter{Locations: []string{"málaga"}, Amenities: []string{"ático"}}, func(p domain.Property) bool {
+		keyed = append(keyed, p.ID)
+		return true
+	})
+	if err != nil || len(keyed) != 1 || keyed[0] != "es-002" {
+		t.Fatalf("non-ASCII keys on upgraded db: %v %v", keyed, err)
+	}
+	if _, _, err := s.UpdateProperty(p, 1); err != nil {
+		t.Fatalf("update on upgraded db: %v", err)
+	}
+	// полнотекстовый индекс пересобран и находит старую строку
+	var found []string
+	err = s.ScanProperties(context.Background(), PropertyFilter{Text: ParseTextQuery("sunny")}, func(p domain.Property) bool {
+		found = append(found, p.ID)
+		return true
+	})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("search on upgraded db: %v %v", found, err)
+	}
+}
+
+func TestMigrate_FailedMigrationRollsBack(t *testing.T) {
+	t.Parallel()
+
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	bad := Migration{Version: 9999, Name: "broken", SQL: `CREATE TABLE half_done (x INTEGER); SELECT * FROM no_such_table;`}
+	if _, err := s.applyMigration(bad); err == nil {
+		t.Fatalf("broken migration succeeded")
+	}
+	var n int
+	_ = s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("partial migration was not rolled back")
+	}
+	_ = s.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = 9999`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("failed migration recorded")
+	}
+}
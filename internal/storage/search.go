@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"html"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// TextQuery is a parsed full-text query: lowercased terms that must all occur
+// in the title or the description. Tokenization mirrors the unicode61 FTS
+// tokenizer, so Matches and the SQL index agree.
+type TextQuery []string
+
+// ParseTextQuery splits s into unique terms; nil when s holds none.
+func ParseTextQuery(s string) TextQuery {
+	var q TextQuery
+	for _, t := range tokenize(s) {
+		term := strings.ToLower(s[t.from:t.to])
+		if !slices.Contains(q, term) {
+			q = append(q, term)
+		}
+	}
+	return q
+}
+
+// titleWeight is how much a title hit outweighs a description hit.
+const titleWeight = 3
+
+// Matches reports whether every term occurs in p's title or description.
+func (q TextQuery) Matches(p domain.Property) bool {
+	title, desc := termCounts(p.Title), termCounts(p.Description)
+	for _, t := range q {
+		if title[t]+desc[t] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Score ranks a matching property: the sum over terms of log(1 + hits), where
+// a title hit counts titleWeight times.
+func (q TextQuery) Score(p domain.Property) float64 {
+	title, desc := termCounts(p.Title), termCounts(p.Description)
+	var score float64
+	for _, t := range q {
+		score += math.Log1p(float64(titleWeight*title[t] + desc[t]))
+	}
+	return score
+}
+
+// Snippet is an HTML-escaped excerpt around the first hit, from the
+// description when it has one, else the title, with terms in <mark> tags.
+func (q TextQuery) Snippet(p domain.Property) string {
+	const before, after = 8, 16 // tokens around the first hit
+
+	text := p.Description
+	toks := tokenize(text)
+	first := q.firstHit(text, toks)
+	if first < 0 {
+		text = p.Title
+		toks = tokenize(text)
+		if first = q.firstHit(text, toks); first < 0 {
+			return ""
+		}
+	}
+
+	start, end := max(0, first-before), min(len(toks), first+after)
+	from, to := 0, len(text)
+	var b strings.Builder
+	if start > 0 {
+		from = toks[start].from
+		b.WriteString("…")
+	}
+	if end < len(toks) {
+		to = toks[end-1].to
+	}
+	pos := from
+	for _, t := range toks[start:end] {
+		if !slices.Contains(q, strings.ToLower(text[t.from:t.to])) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.from]))
+		b.WriteString("<mark>" + html.EscapeString(text[t.from:t.to]) + "</mark>")
+		pos = t.to
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if end < len(toks) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func (q TextQuery) firstHit(text string, toks []span) int {
+	for i, t := range toks {
+		if slices.Contains(q, strings.ToLower(text[t.from:t.to])) {
+			return i
+		}
+	}
+	return -1
+}
+
+// match is the FTS MATCH expression: every term quoted, implicitly ANDed.
+func (q TextQuery) match() string {
+	quoted := make([]string, len(q))
+	for i, t := range q {
+		quoted[i] = `"` + t + `"` // terms hold letters and digits only
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SearchProperties is ListPropertiesFiltered for a filter with Text and a
+// page without Sort: the index finds the matches, and text_score ranks them
+// in SQL with TextQuery.Score, the memory-mode order; best first, then by id.
+func (s *SQLiteStore) SearchProperties(ctx context.Context, f PropertyFilter, pg Page) ([]domain.Property, int, *Cursor, error) {
+	limit := pageLimit(pg.Limit)
+	offset := max(pg.Offset, 0)
+
+	rest := f
+	rest.Text = nil // matched by the join instead
+	where, args := rest.where()
+	args = append([]any{f.Text.String(), f.Text.match()}, args...)
+
+	hits := `WITH hits AS MATERIALIZED (
+  SELECT s.property_id AS pid, text_score(?, title, description) AS score
+  FROM properties_fts JOIN property_search s ON s.search_id = properties_fts.rowid
+  WHERE properties_fts MATCH ?
+)
+`
+	var total int
+	countSQL := hits + `SELECT COUNT(*) FROM properties JOIN hits ON hits.pid = properties.id ` + joinWhere(where)
+	if err := s.db.QueryRowContext(ctx, countSQL, args...).Scan(&total); err != nil {
+		return nil, 0, nil, err
+	}
+
+	if c := pg.Cursor; c != nil {
+		where = append(where, "(hits.score < ? OR (hits.score = ? AND id > ?))")
+		args = append(args, c.Values[0], c.Values[0], c.ID)
+		offset = 0
+	}
+	rowsSQL := hits + selectColumns + `, hits.score
+FROM properties JOIN hits ON hits.pid = properties.id
+` + joinWhere(where) + `
+ORDER BY hits.score DESC, id
+LIMIT ? OFFSET ?`
+	rows, err := s.db.QueryContext(ctx, rowsSQL, append(args, limit+1, offset)...)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.Property
+	var scores []float64
+	for rows.Next() {
+		var score float64
+		p, err := scanProperty(withExtra{rows, []any{&score}})
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		out = append(out, p)
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, nil, err
+	}
+
+	var next *Cursor
+	if len(out) > limit {
+		out = out[:limit]
+		keys := effectiveSort(nil, f)
+		next = &Cursor{Order: cursorOrder(keys, f), Values: []any{scores[limit-1]}, ID: out[limit-1].ID}
+	}
+	return out, total, next, nil
+}
+
+// String joins the terms; ParseTextQuery gives q back from it.
+func (q TextQuery) String() string {
+	return strings.Join(q, " ")
+}
+
+// sqlTextScore is the text_score(query, title, description) SQL function
+// (see sqliteDriver): TextQuery.Score of the parsed query.
+func sqlTextScore(query, title, description string) float64 {
+	return ParseTextQuery(query).Score(domain.Property{Title: title, Description: description})
+}
+
+// withExtra scans the columns after the ones the wrapped row's caller reads.
+type withExtra struct {
+	row   interface{ Scan(dest ...any) error }
+	extra []any
+}
+
+func (w withExtra) Scan(dest ...any) error {
+	return w.row.Scan(append(dest, w.extra...)...)
+}
+
+type span struct{ from, to int }
+
+// tokenize returns the byte spans of letter/digit runs in s.
+func tokenize(s string) []span {
+	var out []span
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsNumber(r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			out = append(out, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		out = append(out, span{start, len(s)})
+	}
+	return out
+}
+
+func termCounts(s string) map[string]int {
+	counts := make(map[string]int)
+	for _, t := range tokenize(s) {
+		counts[strings.ToLower(s[t.from:t.to])]++
+	}
+	return counts
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+func TestSearchIndex_FollowsWrites(t *testing.T) {
+	t.Parallel()
+
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	if err := s.EnsureSchema(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	search := func(q string) []string {
+		ids := []string{}
+		props, _, _, err := s.SearchProperties(context.Background(), PropertyFilter{Text: ParseTextQuery(q)}, Page{Limit: 10})
+		if err != nil {
+			t.Fatalf("search %q: %v", q, err)
+		}
+		for _, p := range props {
+			ids = append(ids, p.ID)
+		}
+		sort.Strings(ids) // only membership matters here
+		return ids
+	}
+
+	if err := s.UpsertMany([]domain.Property{{ID: "a", Title: "Casa", Description: "Terraza con vistas"}}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	b, err := s.CreateProperty(domain.Property{Title: "Ático", Description: "Terraza"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got := search("terraza"); !reflect.DeepEqual(got, []string{"a", b.ID}) {
+		t.Fatalf("after create: %v", got)
+	}
+	// диакритика не снимается: "atico" не находит "Ático"
+	if got := search("ático"); !reflect.DeepEqual(got, []string{b.ID}) || len(search("atico")) != 0 {
+		t.Fatalf("ático=%v atico=%v", got, search("atico"))
+	}
+
+	b.Description = "Piscina"
+	if _, _, err := s.UpdateProperty(b, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := search("terraza"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("after update: %v", got)
+	}
+	if _, err := s.DeleteProperty("a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got := search("terraza"); len(got) != 0 {
+		t.Fatalf("after delete: %v", got)
+	}
+}
+
+func TestTextQuery_Snippet(t *testing.T) {
+	t.Parallel()
+
+	desc := strings.Repeat("word ", 20) + "<b>pool</b> " + strings.Repeat("tail ", 20)
+	got := ParseTextQuery("POOL").Snippet(domain.Property{Title: "T", Description: desc})
+	want := "…word word word word word word word &lt;b&gt;<mark>pool</mark>&lt;/b&gt; " +
+		strings.TrimSpace(strings.Repeat("tail ", 14)) + "…"
+	if got != want {
+		t.Fatalf("snippet=%q\nwant    %q", got, want)
+	}
+
+	// нет совпадения в описании — берётся заголовок
+	if got := ParseTextQuery("loft").Snippet(domain.Property{Title: "Loft", Description: "x"}); got != "<mark>Loft</mark>" {
+		t.Fatalf("title snippet=%q", got)
+	}
+}
+
+func TestSearchProperties_RanksLikeMemory(t *testing.T) {
+	t.Parallel()
+
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	if err := s.EnsureSchema(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	// "garden" в заголовке весит втрое больше, чем в описании
+	props := []domain.Property{{ID: "title", Title: "Garden house", Description: "quiet street, big rooms"}}
+	for i := 0; i < 30; i++ {
+		props = append(props, domain.Property{ID: fmt.Sprintf("d%02d", i), Title: "House",
+			Description: "big rooms" + strings.Repeat(" garden", 1+i%2)})
+	}
+	props = append(props, domain.Property{ID: "other", Title: "Flat", Description: "no match"})
+	if err := s.UpsertMany(props); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	f := PropertyFilter{Text: ParseTextQuery("garden")}
+	all, total, next, err := s.SearchProperties(context.Background(), f, Page{Limit: 100})
+	if err != nil || total != 31 || len(all) != 31 || next != nil {
+		t.Fatalf("search: total=%d len=%d next=%v err=%v", total, len(all), next, err)
+	}
+	if all[0].ID != "title" {
+		t.Fatalf("title hit ranks %s first", all[0].ID)
+	}
+	// больше совпадений — выше (d01 содержит "garden" дважды, d00 — однажды)
+	pos := make(map[string]int)
+	for i, p := range all {
+		pos[p.ID] = i
+	}
+	if pos["d01"] > pos["d00"] {
+		t.Fatalf("order: %v", pos)
+	}
+
+	// курсор по релевантности проходит тот же порядок
+	var walked []string
+	pg := Page{Limit: 7}
+	for {
+		page, _, next, err := s.SearchProperties(context.Background(), f, pg)
+		if err != nil {
+			t.Fatalf("page: %v", err)
+		}
+		for _, p := range page {
+			walked = append(walked, p.ID)
+		}
+		if next == nil {
+			break
+		}
+		if !next.Valid(nil, f) {
+			t.Fatalf("invalid next cursor %+v", next)
+		}
+		pg.Cursor = next
+	}
+	var want []string
+	for _, p := range all {
+		want = append(want, p.ID)
+	}
+	if !reflect.DeepEqual(walked, want) {
+		t.Fatalf("walked %v\nwant   %v", walked, want)
+	}
+
+	// порядок тот же, что у memory: Score по убыванию, затем id
+	sort.SliceStable(props, func(i, j int) bool {
+		si, sj := f.Text.Score(props[i]), f.Text.Score(props[j])
+		if si != sj {
+			return si > sj
+		}
+		return props[i].ID < props[j].ID
+	})
+	if props = props[:len(want)]; !reflect.DeepEqual(propertyIDs(props), want) {
+		t.Fatalf("sql order %v\ngo order  %v", want, propertyIDs(props))
+	}
+}
+
+func propertyIDs(props []domain.Property) []string {
+	out := make([]string, len(props))
+	for i, p := range props {
+		out[i] = p.ID
+	}
+	return out
+}
+
+func TestSearchIndex_SurvivesRowidChanges(t *testing.T) {
+	t.Parallel()
+
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	if err := s.EnsureSchema(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	if err := s.UpsertMany([]domain.Property{
+		{ID: "a", Title: "Alpha"}, {ID: "b", Title: "Bravo"}, {ID: "c", Title: "Charlie"},
+	}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if _, err := s.DeleteProperty("a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	// VACUUM вправе перенумеровать rowid таблицы без INTEGER PRIMARY KEY;
+	// здесь это делается явно, а потом и сам VACUUM
+	if _, err := s.db.Exec(`UPDATE properties SET rowid = rowid + 100`); err != nil {
+		t.Fatalf("renumber: %v", err)
+	}
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+
+	for q, want := range map[string]string{"bravo": "b", "charlie": "c"} {
+		f := PropertyFilter{Text: ParseTextQuery(q)}
+		props, _, _, err := s.SearchProperties(context.Background(), f, Page{Limit: 10})
+		if err != nil || len(props) != 1 || props[0].ID != want {
+			t.Fatalf("search %q after vacuum: %v %v", q, propertyIDs(props), err)
+		}
+		var scanned []string
+		err = s.ScanProperties(context.Background(), f, func(p domain.Property) bool {
+			scanned = append(scanned, p.ID)
+			return true
+		})
+		if err != nil || !reflect.DeepEqual(scanned, []string{want}) {
+			t.Fatalf("filter %q after vacuum: %v %v", q, scanned, err)
+		}
+	}
+}
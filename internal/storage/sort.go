@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
+)
+
+// SortKey is one key of a listing order; ties fall through to the next key
+// and finally to the id.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// sortField is a sortable field as SQL and as Go. value returns a float64, a
+// string (compared as text, like SQLite does) or nil when the property has no
+// value; nil sorts last in both directions. Computed fields depend on the
+// filter: relevance on its Text, distance on its Near point.
+type sortField struct {
+	sql      func(f PropertyFilter) string // nil: Go only
+	nullable bool
+	text     bool
+	value    func(p domain.Property, f PropertyFilter) any
+}
+
+func column(name string) func(PropertyFilter) string {
+	return func(PropertyFilter) string { return name }
+}
+
+// relevanceField orders full-text matches when no sort is given.
+const relevanceField = "relevance"
+
+var sortFields = map[string]sortField{
+	"price":    {sql: column("price"), value: func(p domain.Property, _ PropertyFilter) any { return p.Price }},
+	"area":     {sql: column("area_sqm"), value: func(p domain.Property, _ PropertyFilter) any { return p.AreaSQM }},
+	"bedrooms": {sql: column("bedrooms"), value: func(p domain.Property, _ PropertyFilter) any { return float64(p.Bedrooms) }},
+	"price_per_sqm": {
+		sql:      column("CASE WHEN area_sqm > 0 THEN price / area_sqm END"),
+		nullable: true,
+		value: func(p domain.Property, _ PropertyFilter) any {
+			if p.AreaSQM <= 0 {
+				return nil
+			}
+			return p.Price / p.AreaSQM
+		},
+	},
+	// stored in timeLayout, so the text order is the time order
+	"created_at": {sql: column("created_at"), text: true, value: func(p domain.Property, _ PropertyFilter) any { return formatTime(p.CreatedAt) }},
+	// from f.Near; without coordinates or a Near point there is no value
+	"distance": {
+		sql:      func(f PropertyFilter) string { return distanceSQL(f.Near) },
+		nullable: true,
+		value: func(p domain.Property, f PropertyFilter) any {
+			if f.Near == nil || p.Coordinates == nil {
+				return nil
+			}
+			return DistanceKm(*f.Near, *p.Coordinates)
+		},
+	},
+	relevanceField: {value: func(p domain.Property, f PropertyFilter) any { return f.Text.Score(p) }},
+}
+
+// ParseSort parses "price_per_sqm,-area": comma-separated field names, "-"
+// for descending. The legacy "price_asc" and "price_desc" are accepted too.
+func ParseSort(s string) ([]SortKey, error) {
+	switch s {
+	case "":
+		return nil, nil
+	case "price_asc":
+		return []SortKey{{Field: "price"}}, nil
+	case "price_desc":
+		return []SortKey{{Field: "price", Desc: true}}, nil
+	}
+
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		k := SortKey{Field: strings.TrimSpace(part)}
+		if name, ok := strings.CutPrefix(k.Field, "-"); ok {
+			k.Field, k.Desc = name, true
+		}
+		if f, ok := sortFields[k.Field]; !ok || f.sql == nil {
+			return nil, fmt.Errorf("unknown sort field %q", k.Field)
+		}
+		if seen[k.Field] {
+			return nil, fmt.Errorf("sort field %q repeated", k.Field)
+		}
+		seen[k.Field] = true
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// FormatSort is the inverse of ParseSort.
+func FormatSort(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// effectiveSort is the order a listing actually uses: keys, else relevance
+// for a text query, else just the id.
+func effectiveSort(keys []SortKey, f PropertyFilter) []SortKey {
+	if len(keys) == 0 && len(f.Text) > 0 {
+		return []SortKey{{Field: relevanceField, Desc: true}}
+	}
+	return keys
+}
+
+func sortTuple(p domain.Property, keys []SortKey, f PropertyFilter) []any {
+	out := make([]any, len(keys))
+	for i, k := range keys {
+		out[i] = sortFields[k.Field].value(p, f)
+	}
+	return out
+}
+
+// compareTuples compares two sortTuple results under keys.
+func compareTuples(a, b []any, keys []SortKey) int {
+	for i, k := range keys {
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return 1
+		case b[i] == nil:
+			return -1
+		}
+		var c int
+		switch va := a[i].(type) {
+		case float64:
+			c = cmp.Compare(va, b[i].(float64))
+		case string:
+			c = strings.Compare(va, b[i].(string))
+		}
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// sortProperties orders props by keys, then id; the in-memory twin of orderBy.
+func sortProperties(props []domain.Property, keys []SortKey, f PropertyFilter) {
+	tuples := make(map[string][]any, len(props))
+	for _, p := range props {
+		tuples[p.ID] = sortTuple(p, keys, f)
+	}
+	sort.SliceStable(props, func(i, j int) bool {
+		if c := compareTuples(tuples[props[i].ID], tuples[props[j].ID], keys); c != 0 {
+			return c < 0
+		}
+		return props[i].ID < props[j].ID
+	})
+}
+
+// orderBy renders keys as an ORDER BY clause; without keys it is id order.
+func orderBy(keys []SortKey, f PropertyFilter) string {
+	parts := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		dir := "ASC"
+		if k.Desc {
+			dir = "DESC"
+		}
+		parts = append(parts, sortFields[k.Field].sql(f)+" "+dir+" NULLS LAST")
+	}
+	return "ORDER BY " + strings.Join(append(parts, "id"), ", ")
+}
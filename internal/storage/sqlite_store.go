@@ -1,13 +1,14 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"strings"
 	"time"
-        "strings"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 
 	"github.com/denisok6893-rgb/ai-property-matching/internal/domain"
 )
@@ -16,8 +17,24 @@ type SQLiteStore struct {
 	db *sql.DB
 }
 
+// sqliteDriver is sqlite3 with the store's SQL functions:
+// distance_km(lat, lon, lat0, lon0), which is DistanceKm and NULL for a NULL
+// position, and text_score, which ranks full-text hits like the memory store.
+const sqliteDriver = "sqlite3_properties"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(c *sqlite3.SQLiteConn) error {
+			if err := c.RegisterFunc("distance_km", sqlDistanceKm, true); err != nil {
+				return err
+			}
+			return c.RegisterFunc("text_score", sqlTextScore, true)
+		},
+	})
+}
+
 func OpenSQLite(path string) (*SQLiteStore, error) {
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open(sqliteDriver, path)
 	if err != nil {
 		return nil, err
 	}
@@ -35,35 +52,38 @@ func OpenSQLite(path string) (*SQLiteStore, error) {
 
 func (s *SQLiteStore) Close() error { return s.db.Close() }
 
+// EnsureSchema brings the database to the latest schema version (see Migrate).
 func (s *SQLiteStore) EnsureSchema() error {
-	const createTable = `
-CREATE TABLE IF NOT EXISTS properties (
-  id TEXT PRIMARY KEY,
-  title TEXT NOT NULL,
-  location TEXT NOT NULL,
-  price REAL NOT NULL,
-  bedrooms INTEGER NOT NULL,
-  bathrooms INTEGER NOT NULL,
-  area_sqm REAL NOT NULL,
-  description TEXT NOT NULL DEFAULT '',
-  image_urls_json TEXT NOT NULL DEFAULT '[]',
-  amenities_json TEXT NOT NULL DEFAULT '[]',
-  features_json TEXT NOT NULL DEFAULT '{}'
-);
-`
-	if _, err := s.db.Exec(createTable); err != nil {
-		return err
-	}
+	_, err := s.Migrate()
+	return err
+}
 
-	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_properties_location ON properties(location);`); err != nil {
-		return err
-	}
-	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_properties_price ON properties(price);`); err != nil {
-		return err
-	}
+// propertyColumns are the properties table columns in scanProperty order,
+// followed there by the amenities list (see selectProperties).
+var propertyColumns = `id, title, location, price, bedrooms, bathrooms, area_sqm, description, image_urls_json, ` +
+	strings.Join(featureColumns, ", ") + `, lat, lon, version, created_at, updated_at`
 
-	return nil
-}
+// selectColumns reads propertyColumns plus the amenities as a JSON array.
+var selectColumns = `SELECT ` + propertyColumns + `,
+  (SELECT json_group_array(name) FROM (
+    SELECT name FROM property_amenities a WHERE a.property_id = properties.id ORDER BY position
+  )) AS amenities_json`
+
+var selectProperties = selectColumns + "\nFROM properties"
+
+// writeColumns are propertyColumns plus the derived columns only filters read.
+var writeColumns = propertyColumns + `, location_key`
+
+var insertProperty = `INSERT INTO properties (` + writeColumns + `)
+VALUES (?` + strings.Repeat(", ?", 14+len(featureColumns)) + `)`
+
+var (
+	// ErrVersionConflict is returned by UpdateProperty when the stored version
+	// differs from the expected one.
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrDuplicateID is returned by CreateProperty for an id that is taken.
+	ErrDuplicateID = errors.New("property id already exists")
+)
 
 func (s *SQLiteStore) CountProperties() (int, error) {
 	var n int
@@ -79,25 +99,32 @@ func (s *SQLiteStore) UpsertMany(items []domain.Property) error {
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := tx.Prepare(`
-INSERT OR IGNORE INTO properties
-(id, title, location, price, bedrooms, bathrooms, area_sqm, description, image_urls_json, amenities_json, features_json)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-`)
+	stmt, err := tx.Prepare(`INSERT OR IGNORE` + strings.TrimPrefix(insertProperty, `INSERT`))
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
+	now := time.Now().UTC()
 	for _, p := range items {
-		img, _ := json.Marshal(p.ImageURLs)
-		am, _ := json.Marshal(p.Amenities)
-		ft, _ := json.Marshal(p.Features)
-
-		if _, err := stmt.Exec(
-			p.ID, p.Title, p.Location, p.Price, p.Bedrooms, p.Bathrooms, p.AreaSQM,
-			p.Description, string(img), string(am), string(ft),
-		); err != nil {
+		if p.Version <= 0 {
+			p.Version = 1
+		}
+		if p.UpdatedAt.IsZero() {
+			p.UpdatedAt = now
+		}
+		if p.CreatedAt.IsZero() {
+			p.CreatedAt = p.UpdatedAt
+		}
+
+		res, err := stmt.Exec(propertyArgs(p)...)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // id already present
+		}
+		if err := putAmenities(tx, p.ID, p.Amenities); err != nil {
 			return err
 		}
 	}
@@ -106,189 +133,287 @@ VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 
 func (s *SQLiteStore) CreateProperty(p domain.Property) (domain.Property, error) {
 	if p.ID == "" {
-		p.ID = fmt.Sprintf("p-%d", time.Now().UnixNano())
+		p.ID = NewPropertyID()
 	}
-	img, _ := json.Marshal(p.ImageURLs)
-	am, _ := json.Marshal(p.Amenities)
-	ft, _ := json.Marshal(p.Features)
-
-	_, err := s.db.Exec(`
-INSERT INTO properties
-(id, title, location, price, bedrooms, bathrooms, area_sqm, description, image_urls_json, amenities_json, features_json)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-`,
-		p.ID, p.Title, p.Location, p.Price, p.Bedrooms, p.Bathrooms, p.AreaSQM,
-		p.Description, string(img), string(am), string(ft),
-	)
-	return p, err
+	p.Version, p.UpdatedAt = 1, time.Now().UTC()
+	p.CreatedAt = p.UpdatedAt
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return domain.Property{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(insertProperty, propertyArgs(p)...)
+	var se sqlite3.Error
+	if errors.As(err, &se) && se.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return domain.Property{}, ErrDuplicateID
+	}
+	if err != nil {
+		return domain.Property{}, err
+	}
+	if err := putAmenities(tx, p.ID, p.Amenities); err != nil {
+		return domain.Property{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Property{}, err
+	}
+	return p, nil
+}
+
+// updateSkip are the writeColumns UpdateProperty leaves alone.
+var updateSkip = map[string]bool{"id": true, "version": true, "created_at": true}
+
+// updateProperty sets the other writeColumns, in order, from updateArgs.
+var updateProperty = func() string {
+	var set []string
+	for _, c := range strings.Split(writeColumns, ", ") {
+		if !updateSkip[c] {
+			set = append(set, c+" = ?")
+		}
+	}
+	return `UPDATE properties SET ` + strings.Join(set, ", ") + `, version = version + 1
+WHERE id = ? AND (? = 0 OR version = ?)
+RETURNING version, created_at`
+}()
+
+func updateArgs(p domain.Property) []any {
+	var out []any
+	all := propertyArgs(p)
+	for i, c := range strings.Split(writeColumns, ", ") {
+		if !updateSkip[c] {
+			out = append(out, all[i])
+		}
+	}
+	return out
+}
+
+// UpdateProperty replaces every column of the property with p.ID, bumps its
+// version and returns the stored result. With ifVersion > 0 the update only
+// happens if the stored version still equals it, otherwise ErrVersionConflict.
+func (s *SQLiteStore) UpdateProperty(p domain.Property, ifVersion int64) (domain.Property, bool, error) {
+	p.UpdatedAt = time.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return domain.Property{}, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var createdAt string
+	args := append(updateArgs(p), p.ID, ifVersion, ifVersion)
+	err = tx.QueryRow(updateProperty, args...).Scan(&p.Version, &createdAt)
+	if err == nil {
+		p.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+		if err := putAmenities(tx, p.ID, p.Amenities); err != nil {
+			return domain.Property{}, false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return domain.Property{}, false, err
+		}
+		return p, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return domain.Property{}, false, err
+	}
+
+	// nothing updated: either no such id or a stale version
+	var cur int64
+	err = tx.QueryRow(`SELECT version FROM properties WHERE id = ?`, p.ID).Scan(&cur)
+	if err == sql.ErrNoRows {
+		return domain.Property{}, false, nil
+	}
+	if err != nil {
+		return domain.Property{}, false, err
+	}
+	return domain.Property{}, true, ErrVersionConflict
 }
 
 func (s *SQLiteStore) DeleteProperty(id string) (bool, error) {
-	res, err := s.db.Exec(`DELETE FROM properties WHERE id = ?`, id)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// explicit: foreign_keys is a per-connection pragma, the cascade may be off
+	if _, err := tx.Exec(`DELETE FROM property_amenities WHERE property_id = ?`, id); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`DELETE FROM properties WHERE id = ?`, id)
 	if err != nil {
 		return false, err
 	}
 	aff, _ := res.RowsAffected()
-	return aff > 0, nil
+	return aff > 0, tx.Commit()
 }
 
 func (s *SQLiteStore) GetProperty(id string) (domain.Property, bool, error) {
-	var p domain.Property
-	var imgJSON, amJSON, ftJSON string
-
-	err := s.db.QueryRow(`
-SELECT id, title, location, price, bedrooms, bathrooms, area_sqm, description, image_urls_json, amenities_json, features_json
-FROM properties WHERE id = ?
-`, id).Scan(
-		&p.ID, &p.Title, &p.Location, &p.Price, &p.Bedrooms, &p.Bathrooms, &p.AreaSQM,
-		&p.Description, &imgJSON, &amJSON, &ftJSON,
-	)
+	p, err := scanProperty(s.db.QueryRow(selectProperties+` WHERE id = ?`, id))
 	if err == sql.ErrNoRows {
 		return domain.Property{}, false, nil
 	}
 	if err != nil {
 		return domain.Property{}, false, err
 	}
-
-	_ = json.Unmarshal([]byte(imgJSON), &p.ImageURLs)
-	_ = json.Unmarshal([]byte(amJSON), &p.Amenities)
-	_ = json.Unmarshal([]byte(ftJSON), &p.Features)
-
 	return p, true, nil
 }
 
 func (s *SQLiteStore) ListProperties(limit, offset int) ([]domain.Property, int, error) {
-	if limit <= 0 {
-		limit = 20
+	props, total, _, err := s.ListPropertiesFiltered(PropertyFilter{}, Page{Limit: limit, Offset: offset})
+	return props, total, err
+}
+
+// ListPropertiesFiltered returns one page of properties matching f, the total
+// number of matches and, when more rows follow, the cursor of the next page.
+// Rows come by pg.Sort, then id; f.Text does not rank here (SearchProperties).
+func (s *SQLiteStore) ListPropertiesFiltered(f PropertyFilter, pg Page) ([]domain.Property, int, *Cursor, error) {
+	limit := pageLimit(pg.Limit)
+	offset := max(pg.Offset, 0)
+
+	where, args := f.where()
+
+	// total count with same WHERE
+	countSQL := "SELECT COUNT(*) FROM properties " + joinWhere(where)
+	var total int
+	if err := s.db.QueryRow(countSQL, args...).Scan(&total); err != nil {
+		return nil, 0, nil, err
 	}
-	if offset < 0 {
+
+	if pg.Cursor != nil {
+		cond, condArgs := pg.Cursor.afterClause(pg.Sort, f)
+		where = append(where, cond)
+		args = append(args, condArgs...)
 		offset = 0
 	}
 
-	total, err := s.CountProperties()
-	if err != nil {
-		return nil, 0, err
-	}
+	// one extra row tells whether there is a next page
+	rowsSQL := selectProperties + "\n" + joinWhere(where) + "\n" + orderBy(pg.Sort, f) + "\nLIMIT ? OFFSET ?"
+	rowsArgs := append(append([]any{}, args...), limit+1, offset)
 
-	rows, err := s.db.Query(`
-SELECT id, title, location, price, bedrooms, bathrooms, area_sqm, description, image_urls_json, amenities_json, features_json
-FROM properties
-ORDER BY id
-LIMIT ? OFFSET ?
-`, limit, offset)
+	rows, err := s.db.Query(rowsSQL, rowsArgs...)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	defer rows.Close()
 
 	var out []domain.Property
 	for rows.Next() {
-		var p domain.Property
-		var imgJSON, amJSON, ftJSON string
-
-		if err := rows.Scan(
-			&p.ID, &p.Title, &p.Location, &p.Price, &p.Bedrooms, &p.Bathrooms, &p.AreaSQM,
-			&p.Description, &imgJSON, &amJSON, &ftJSON,
-		); err != nil {
-			return nil, 0, err
+		p, err := scanProperty(rows)
+		if err != nil {
+			return nil, 0, nil, err
 		}
-		_ = json.Unmarshal([]byte(imgJSON), &p.ImageURLs)
-		_ = json.Unmarshal([]byte(amJSON), &p.Amenities)
-		_ = json.Unmarshal([]byte(ftJSON), &p.Features)
-
 		out = append(out, p)
 	}
-	return out, total, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, 0, nil, err
+	}
+
+	var next *Cursor
+	if len(out) > limit {
+		out = out[:limit]
+		next = cursorAt(out[limit-1], pg.Sort, f)
+	}
+	return out, total, next, nil
 }
 
-func (s *SQLiteStore) ListPropertiesFiltered(
-	limit, offset int,
-	location string,
-	minPrice, maxPrice float64,
-	minBedrooms int,
-	sortBy string,
-) ([]domain.Property, int, error) {
-	if limit <= 0 {
-		limit = 20
-	}
-	if offset < 0 {
-		offset = 0
+// ScanProperties streams properties matching f in id order without loading
+// the whole table; fn returning false stops the scan.
+func (s *SQLiteStore) ScanProperties(ctx context.Context, f PropertyFilter, fn func(domain.Property) bool) error {
+	whereSQL, args := whereClause(f)
+	rows, err := s.db.QueryContext(ctx, selectProperties+"\n"+whereSQL+"\nORDER BY id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanProperty(rows)
+		if err != nil {
+			return err
+		}
+		if !fn(p) {
+			return nil
+		}
 	}
+	return rows.Err()
+}
 
-	// WHERE builder
-	where := make([]string, 0, 4)
-	args := make([]any, 0, 8)
+func whereClause(f PropertyFilter) (string, []any) {
+	where, args := f.where()
+	return joinWhere(where), args
+}
 
-	if strings.TrimSpace(location) != "" {
-		// contains, case-insensitive
-		where = append(where, "LOWER(location) LIKE '%' || LOWER(?) || '%'")
-		args = append(args, location)
+func joinWhere(where []string) string {
+	if len(where) == 0 {
+		return ""
 	}
-	if minPrice > 0 {
-		where = append(where, "price >= ?")
-		args = append(args, minPrice)
+	return "WHERE " + strings.Join(where, " AND ")
+}
+
+// propertyArgs are the values for writeColumns.
+func propertyArgs(p domain.Property) []any {
+	img, _ := json.Marshal(p.ImageURLs)
+	args := []any{
+		p.ID, p.Title, p.Location, p.Price, p.Bedrooms, p.Bathrooms, p.AreaSQM,
+		p.Description, string(img),
 	}
-	if maxPrice > 0 {
-		where = append(where, "price <= ?")
-		args = append(args, maxPrice)
+	for _, key := range featureColumns {
+		args = append(args, *domain.FeaturePtr(&p.Features, key))
 	}
-	if minBedrooms > 0 {
-		where = append(where, "bedrooms >= ?")
-		args = append(args, minBedrooms)
+	var lat, lon any // NULL without coordinates
+	if p.Coordinates != nil {
+		lat, lon = p.Coordinates.Lat, p.Coordinates.Lon
 	}
+	return append(args, lat, lon, p.Version, formatTime(p.CreatedAt), formatTime(p.UpdatedAt), locationKey(p.Location))
+}
 
-	whereSQL := ""
-	if len(where) > 0 {
-		whereSQL = "WHERE " + strings.Join(where, " AND ")
+// putAmenities replaces the amenity rows of a property.
+func putAmenities(tx *sql.Tx, id string, amenities []string) error {
+	if _, err := tx.Exec(`DELETE FROM property_amenities WHERE property_id = ?`, id); err != nil {
+		return err
 	}
-
-	orderSQL := "ORDER BY id"
-	switch sortBy {
-	case "price_asc":
-		orderSQL = "ORDER BY price ASC"
-	case "price_desc":
-		orderSQL = "ORDER BY price DESC"
+	for i, a := range amenities {
+		if _, err := tx.Exec(`INSERT INTO property_amenities (property_id, position, name, name_key) VALUES (?, ?, ?, ?)`,
+			id, i, a, amenityKey(a)); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// total count with same WHERE
-	countSQL := "SELECT COUNT(*) FROM properties " + whereSQL
-	var total int
-	if err := s.db.QueryRow(countSQL, args...).Scan(&total); err != nil {
-		return nil, 0, err
+func scanProperty(row interface{ Scan(dest ...any) error }) (domain.Property, error) {
+	var p domain.Property
+	var imgJSON, amJSON, createdAt, updatedAt string
+	var lat, lon sql.NullFloat64
+	dest := []any{
+		&p.ID, &p.Title, &p.Location, &p.Price, &p.Bedrooms, &p.Bathrooms, &p.AreaSQM,
+		&p.Description, &imgJSON,
 	}
-
-	// rows
-	rowsSQL := `
-SELECT id, title, location, price, bedrooms, bathrooms, area_sqm, description, image_urls_json, amenities_json, features_json
-FROM properties
-` + whereSQL + "\n" + orderSQL + "\nLIMIT ? OFFSET ?"
-
-	rowsArgs := append(append([]any{}, args...), limit, offset)
-
-	rows, err := s.db.Query(rowsSQL, rowsArgs...)
-	if err != nil {
-		return nil, 0, err
+	for _, key := range featureColumns {
+		dest = append(dest, domain.FeaturePtr(&p.Features, key))
 	}
-	defer rows.Close()
-
-	var out []domain.Property
-	for rows.Next() {
-		var p domain.Property
-		var imgJSON, amJSON, ftJSON string
-		if err := rows.Scan(
-			&p.ID, &p.Title, &p.Location, &p.Price, &p.Bedrooms, &p.Bathrooms, &p.AreaSQM,
-			&p.Description, &imgJSON, &amJSON, &ftJSON,
-		); err != nil {
-			return nil, 0, err
-		}
-		_ = json.Unmarshal([]byte(imgJSON), &p.ImageURLs)
-		_ = json.Unmarshal([]byte(amJSON), &p.Amenities)
-		_ = json.Unmarshal([]byte(ftJSON), &p.Features)
-		out = append(out, p)
+	dest = append(dest, &lat, &lon, &p.Version, &createdAt, &updatedAt, &amJSON)
+	if err := row.Scan(dest...); err != nil {
+		return domain.Property{}, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, 0, err
+	_ = json.Unmarshal([]byte(imgJSON), &p.ImageURLs)
+	_ = json.Unmarshal([]byte(amJSON), &p.Amenities)
+	if len(p.Amenities) == 0 {
+		p.Amenities = nil // as written for a property without amenities
+	}
+	if lat.Valid && lon.Valid {
+		p.Coordinates = &domain.GeoPoint{Lat: lat.Float64, Lon: lon.Float64}
 	}
+	p.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	p.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+	return p, nil
+}
+
+// timeLayout is RFC 3339 with a fixed-width fraction, so stored UTC
+// timestamps sort as text; time.RFC3339Nano parses it.
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
 
-	return out, total, nil
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
 }